@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "解析キャッシュの管理",
+	Long:  `ファイル単位の解析結果と --incremental で保存したインデックスを保存するキャッシュ (ユーザーキャッシュディレクトリ配下の rev-callgraph) を管理します。`,
+}
+
+var cacheStatsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "解析キャッシュの統計情報を出力",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		s, err := openCache()
+		if err != nil {
+			return fmt.Errorf("キャッシュを開けません: %w", err)
+		}
+		st, err := s.Stats()
+		if err != nil {
+			return fmt.Errorf("キャッシュの集計失敗: %w", err)
+		}
+		w := cmd.OutOrStdout()
+		fmt.Fprintf(w, "dir: %s\n", st.Dir)
+		fmt.Fprintf(w, "entries: %d\n", st.Entries)
+		fmt.Fprintf(w, "bytes: %d\n", st.Bytes)
+		return nil
+	},
+}
+
+var cacheClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "解析キャッシュを削除",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		s, err := openCache()
+		if err != nil {
+			return fmt.Errorf("キャッシュを開けません: %w", err)
+		}
+		if err := s.Clear(); err != nil {
+			return fmt.Errorf("キャッシュの削除失敗: %w", err)
+		}
+		if err := os.RemoveAll(indexDir(s)); err != nil {
+			return fmt.Errorf("インデックスの削除失敗: %w", err)
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "cleared: %s\n", s.Dir())
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(cacheCmd)
+	cacheCmd.AddCommand(cacheStatsCmd)
+	cacheCmd.AddCommand(cacheClearCmd)
+}
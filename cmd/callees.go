@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/meian/rev-callgraph/internal/callgraph"
+	"github.com/meian/rev-callgraph/internal/progress"
+	"github.com/meian/rev-callgraph/internal/symbol"
+	"github.com/spf13/cobra"
+)
+
+// calleesp は callees サブコマンドのフラグ値を保持します
+var calleesp struct {
+	// MaxDepth は探索の最大深さ
+	MaxDepth int
+	// External は標準ライブラリやワークスペース外のモジュールの呼び出し先も出力するかどうか
+	External bool
+}
+
+var calleesCmd = &cobra.Command{
+	Use:   "callees <target>",
+	Short: "関数が呼び出している関数の順方向グラフを出力",
+	Long: `指定した関数/メソッドから呼び出されている関数/メソッドを、ワークスペース内に限って再帰的に探索し、--format の形式で出力します。
+--external を指定した場合は、標準ライブラリやワークスペース外のモジュールの呼び出し先も末端として出力します。`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := commandContext(cmd)
+		target := args[0]
+
+		f, err := symbol.ParseFunction(target)
+		if err != nil {
+			return fmt.Errorf("targetの分解失敗: %w", err)
+		}
+		mods, err := scanWorkspace(ctx, cmd)
+		if err != nil {
+			return err
+		}
+		mod, def, err := mods.LookupDefinition(ctx, f)
+		if err != nil {
+			return fmt.Errorf("targetの存在確認失敗: %w", err)
+		}
+		if mod == nil {
+			return fmt.Errorf("targetが見つかりません: %s", target)
+		}
+		progress.Msgf(ctx, "target %s defined at %s", target, def.Pos)
+
+		root, err := callgraph.CalleesGraph(ctx, *mod, target, *mods, calleesp.MaxDepth, calleesp.External)
+		if root == nil {
+			return fmt.Errorf("呼び出し先の取得失敗: %w", err)
+		}
+		if perr := printGraph(ctx, root, calleesp.MaxDepth); perr != nil {
+			return perr
+		}
+		if err != nil {
+			cmd.SilenceUsage = true
+			return fmt.Errorf("呼び出し先の探索が中断されました (未展開 %d ノード): %w", len(root.Truncated()), err)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(calleesCmd)
+	calleesCmd.Flags().IntVar(&calleesp.MaxDepth, "max-depth", 0, "探索の最大深さ (0は制限なし)")
+	calleesCmd.Flags().BoolVar(&calleesp.External, "external", false, "標準ライブラリやワークスペース外のモジュールの呼び出し先も末端として出力する")
+}
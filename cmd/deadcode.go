@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/meian/rev-callgraph/internal/callgraph"
+	"github.com/meian/rev-callgraph/internal/format"
+	"github.com/meian/rev-callgraph/internal/stats"
+	"github.com/spf13/cobra"
+)
+
+// deadcodep は deadcode サブコマンドのフラグ値を保持します
+var deadcodep struct {
+	// Roots は到達可能性の起点とするエントリポイントの種別または関数名
+	Roots []string
+}
+
+var deadcodeCmd = &cobra.Command{
+	Use:   "deadcode",
+	Short: "エントリポイントから到達できない関数を出力",
+	Long: `ワークスペース全体のコールグラフを構築し、--roots に指定した起点から呼び出しを辿って到達できない関数/メソッドを
+モジュール、パッケージごとに定義位置付きで --format の形式で出力します。
+呼び出さずに値として参照した関数/メソッド (コールバックやメソッド値) も参照元から到達できるものとします。
+型を解決しないため、同じ名前の変数やフィールドの参照も関数/メソッドの参照とみなします。
+ドキュメントコメントに //` + callgraph.IgnoreDirective + ` を付けた関数/メソッドは報告せず、起点として扱います。`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := commandContext(cmd)
+		p, err := format.NewReportPrinter[format.DeadCode](rootp.Format)
+		if err != nil {
+			return err
+		}
+		mods, err := scanWorkspace(ctx, cmd)
+		if err != nil {
+			return err
+		}
+		_, dead, err := callgraph.DeadCode(ctx, *mods, deadcodep.Roots)
+		if err != nil && ctx.Err() != nil {
+			// 一部のファイルのみから構築したグラフでは到達できる関数も報告されるため、途中までの結果は出力しない
+			cmd.SilenceUsage = true
+			return fmt.Errorf("到達できない関数の探索が中断されました (途中までの結果は出力しません): %w", err)
+		}
+		if err != nil {
+			return fmt.Errorf("到達できない関数の取得失敗: %w", err)
+		}
+		defer stats.Start(ctx, stats.PhasePrint, rootp.Format)()
+		return p.Print(dead)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(deadcodeCmd)
+	deadcodeCmd.Flags().StringSliceVar(&deadcodep.Roots, "roots", callgraph.DefaultDeadCodeRoots,
+		"到達可能性の起点: エントリポイントの種別 (main, init, test, handler, exported) または関数名 (複数指定可)")
+}
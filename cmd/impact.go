@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/meian/rev-callgraph/internal/astquery"
+	"github.com/meian/rev-callgraph/internal/callgraph"
+	"github.com/meian/rev-callgraph/internal/format"
+	"github.com/meian/rev-callgraph/internal/git"
+	"github.com/meian/rev-callgraph/internal/progress"
+	"github.com/meian/rev-callgraph/internal/stats"
+	"github.com/spf13/cobra"
+)
+
+// impactp は impact サブコマンドのフラグ値を保持します
+var impactp struct {
+	// Base は比較の基準とするリビジョン
+	Base string
+	// MaxDepth は逆探索の最大深さ
+	MaxDepth int
+	// Tests は影響を受けるテスト関数を go test のコマンドラインとして出力するかどうか
+	Tests bool
+}
+
+var impactCmd = &cobra.Command{
+	Use:   "impact",
+	Short: "git の差分で変更された関数の影響範囲を出力",
+	Long: `--base と HEAD のマージベースから作業ツリーまでの git diff で変更された行を含む関数/メソッドを求め、
+それらの呼び出し元をまとめて逆方向に辿ります。
+影響を受けるエントリポイント (到達する変更された関数と経路の一例を含む)、パッケージ、main パッケージを --format の形式で出力します。`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := commandContext(cmd)
+		p, err := format.NewReportPrinter[format.Impact](rootp.Format)
+		if err != nil {
+			return err
+		}
+		if impactp.Tests {
+			if _, err := format.NewReportPrinter[format.Tests](rootp.Format); err != nil {
+				return err
+			}
+		}
+
+		mods, err := scanWorkspace(ctx, cmd)
+		if err != nil {
+			return err
+		}
+		dir, err := workspaceDir()
+		if err != nil {
+			return err
+		}
+		progress.Msgf(ctx, "diff %s against %s", dir, impactp.Base)
+		changes, err := git.ChangedLines(ctx, dir, impactp.Base)
+		if err != nil {
+			return fmt.Errorf("差分の取得失敗: %w", err)
+		}
+		changed, err := astquery.ChangedFunctions(ctx, changes, *mods)
+		if err != nil {
+			return fmt.Errorf("変更された関数の取得失敗: %w", err)
+		}
+
+		ctx, err = indexWorkspace(ctx)
+		if err != nil {
+			return err
+		}
+
+		g, impact, err := callgraph.Impact(ctx, changed, *mods, impactp.MaxDepth)
+		if impact == nil {
+			return fmt.Errorf("呼び出し元の取得失敗: %w", err)
+		}
+		if g == nil {
+			fmt.Fprintf(cmd.ErrOrStderr(), "%s からの変更を含む関数は見つかりませんでした\n", impactp.Base)
+			return nil
+		}
+		var perr error
+		if impactp.Tests {
+			perr = printTests(ctx, g, *mods)
+		} else {
+			end := stats.Start(ctx, stats.PhasePrint, rootp.Format)
+			perr = p.Print(format.Impact{Graph: g, Impact: impact})
+			end()
+		}
+		if perr != nil {
+			return perr
+		}
+		if err != nil {
+			cmd.SilenceUsage = true
+			return fmt.Errorf("呼び出し元の探索が中断されました (未展開 %d ノード): %w", len(g.Truncated()), err)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(impactCmd)
+	impactCmd.Flags().StringVar(&impactp.Base, "base", "origin/main", "比較の基準とするリビジョン (HEAD とのマージベースからの差分を対象とする)")
+	impactCmd.Flags().BoolVar(&impactp.Tests, "tests", false, "影響を受けるテスト関数をパッケージごとに go test のコマンドラインとして出力する (--format json も可)")
+	impactCmd.Flags().IntVar(&impactp.MaxDepth, "max-depth", 0, "逆探索の最大深さ (0は制限なし)")
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/meian/rev-callgraph/internal/callgraph"
+	"github.com/meian/rev-callgraph/internal/progress"
+	"github.com/spf13/cobra"
+)
+
+// importersp は importers サブコマンドのフラグ値を保持します
+var importersp struct {
+	// MaxDepth は逆探索の最大深さ
+	MaxDepth int
+}
+
+var importersCmd = &cobra.Command{
+	Use:   "importers <package>",
+	Short: "パッケージをインポートしているパッケージの逆方向グラフを出力",
+	Long:  `指定したパッケージを直接または間接的にインポートしているワークスペース内のパッケージを、mainパッケージに至るまで逆方向に探索します。`,
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := commandContext(cmd)
+		pkg := args[0]
+
+		mods, err := scanWorkspace(ctx, cmd)
+		if err != nil {
+			return err
+		}
+
+		// 対象パッケージが含まれるモジュールを検出
+		mod, ok := mods.FindByPackage(pkg)
+		if !ok {
+			return fmt.Errorf("packageが見つかりません: %s", pkg)
+		}
+		dir, err := mod.PackageDir(pkg)
+		if err != nil {
+			return fmt.Errorf("packageが見つかりません: %s", pkg)
+		}
+		if fi, err := os.Stat(dir); err != nil || !fi.IsDir() {
+			return fmt.Errorf("packageが見つかりません: %s", pkg)
+		}
+		progress.Msgf(ctx, "package %s in module %s", pkg, mod.Path)
+
+		root, err := callgraph.ImportersGraph(ctx, *mod, pkg, *mods, importersp.MaxDepth)
+		if root == nil {
+			return fmt.Errorf("インポート元の取得失敗: %w", err)
+		}
+		if perr := printGraph(ctx, root, importersp.MaxDepth); perr != nil {
+			return perr
+		}
+		if err != nil {
+			cmd.SilenceUsage = true
+			return fmt.Errorf("インポート元の探索が中断されました (未展開 %d ノード): %w", len(root.Truncated()), err)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(importersCmd)
+	importersCmd.Flags().IntVar(&importersp.MaxDepth, "max-depth", 0, "逆探索の最大深さ (0は制限なし)")
+}
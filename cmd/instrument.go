@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"runtime/pprof"
+
+	"github.com/meian/rev-callgraph/internal/stats"
+	"github.com/spf13/cobra"
+)
+
+// instr は実行中の計測の状態を保持します
+var instr struct {
+	// rec は --stats または --trace-out 指定時の計測結果
+	rec *stats.Recorder
+	// cpu は --cpuprofile 指定時の出力先
+	cpu *os.File
+}
+
+// startInstrumentation はフラグに応じて計測と CPU プロファイルを開始し、
+// コマンドのコンテキストに stats.Recorder を設定します
+func startInstrumentation(cmd *cobra.Command) error {
+	if rootp.Stats || rootp.TraceOut != "" {
+		instr.rec = stats.NewRecorder()
+		cmd.SetContext(stats.WithRecorder(cmd.Context(), instr.rec))
+	}
+	if rootp.CPUProfile != "" {
+		f, err := os.Create(rootp.CPUProfile)
+		if err != nil {
+			return fmt.Errorf("CPUプロファイルの作成失敗: %w", err)
+		}
+		if err := pprof.StartCPUProfile(f); err != nil {
+			f.Close()
+			return fmt.Errorf("CPUプロファイルの開始失敗: %w", err)
+		}
+		instr.cpu = f
+	}
+	return nil
+}
+
+// finishInstrumentation は計測を終了し、集計を w に、プロファイルとトレースをファイルに出力します
+// コマンドが失敗した場合もそれまでの計測結果を出力します
+func finishInstrumentation(w io.Writer) error {
+	var errs []error
+	if instr.cpu != nil {
+		pprof.StopCPUProfile()
+		errs = append(errs, instr.cpu.Close())
+		instr.cpu = nil
+	}
+	if rootp.MemProfile != "" {
+		errs = append(errs, writeMemProfile(rootp.MemProfile))
+	}
+	if instr.rec != nil {
+		if rootp.TraceOut != "" {
+			errs = append(errs, writeTrace(rootp.TraceOut, instr.rec))
+		}
+		if rootp.Stats {
+			instr.rec.Print(w)
+		}
+		instr.rec = nil
+	}
+	return errors.Join(errs...)
+}
+
+// writeMemProfile はヒーププロファイルを path に出力します
+func writeMemProfile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("メモリプロファイルの作成失敗: %w", err)
+	}
+	defer f.Close()
+	// 直近の割り当て状況を反映させる
+	runtime.GC()
+	if err := pprof.WriteHeapProfile(f); err != nil {
+		return fmt.Errorf("メモリプロファイルの出力失敗: %w", err)
+	}
+	return nil
+}
+
+// writeTrace は計測した区間を Chrome のトレースイベント形式で path に出力します
+func writeTrace(path string, rec *stats.Recorder) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("トレースファイルの作成失敗: %w", err)
+	}
+	defer f.Close()
+	if err := rec.WriteTrace(f); err != nil {
+		return fmt.Errorf("トレースの出力失敗: %w", err)
+	}
+	return nil
+}
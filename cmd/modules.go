@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/meian/rev-callgraph/internal/format"
+	"github.com/meian/rev-callgraph/internal/gomod"
+	"github.com/spf13/cobra"
+)
+
+// modulesp は modules サブコマンドのフラグ値を保持します
+var modulesp struct {
+	// Graph は出力する依存グラフの向き
+	// 空の場合はモジュール一覧を出力する
+	Graph string
+}
+
+var modulesCmd = &cobra.Command{
+	Use:   "modules [module]",
+	Short: "ワークスペース内のモジュール一覧と依存グラフを出力",
+	Long: `ワークスペース内のモジュール一覧 (パス, ルート, Goバージョン, require, replace) を出力します。
+--format は tree (テキスト) と json を指定できます。
+--graph を指定した場合はモジュール間の依存グラフを --format (tree / json / dot) の形式で出力します。
+module を省略した場合はグラフの起点となる全てのモジュールについて出力します。`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := commandContext(cmd)
+		// モジュール一覧はテキストと JSON のみ出力できる
+		if modulesp.Graph == "" && rootp.Format != "tree" && rootp.Format != "json" {
+			return fmt.Errorf("unsupported format for modules: %s (--graph を指定した場合のみ使えます)", rootp.Format)
+		}
+		mods, err := scanWorkspace(ctx, cmd)
+		if err != nil {
+			return err
+		}
+
+		var targets []gomod.Module
+		if len(args) > 0 {
+			mod, ok := mods.Get(args[0])
+			if !ok {
+				return fmt.Errorf("モジュールが見つかりません: %s", args[0])
+			}
+			targets = []gomod.Module{mod}
+		}
+
+		switch modulesp.Graph {
+		case "":
+			if targets == nil {
+				targets = mods.Sorted()
+			}
+			if rootp.Format == "json" {
+				return printModulesJSON(cmd.OutOrStdout(), targets)
+			}
+			printModules(cmd.OutOrStdout(), targets)
+			return nil
+		case "deps", "rdeps":
+			reverse := modulesp.Graph == "rdeps"
+			if targets == nil {
+				targets = mods.Roots(reverse)
+			}
+			p, err := format.NewPrinter(rootp.Format, rootp.JSONStyle, 0)
+			if err != nil {
+				return err
+			}
+			for _, mod := range targets {
+				g := mods.DepsGraph(mod)
+				if reverse {
+					g = mods.RevDepsGraph(mod)
+				}
+				if err := p.Print(g); err != nil {
+					return err
+				}
+			}
+			return nil
+		default:
+			return fmt.Errorf("unsupported graph: %s", modulesp.Graph)
+		}
+	},
+}
+
+// printModules はモジュール一覧をテキストで出力します
+func printModules(w io.Writer, mods []gomod.Module) {
+	for _, m := range mods {
+		fmt.Fprintln(w, m.Path)
+		fmt.Fprintf(w, "  root: %s\n", m.Root)
+		if m.IsVendored() {
+			fmt.Fprintf(w, "  vendored by: %s %s\n", m.VendoredBy, m.Version)
+		}
+		if m.GoVersion != "" {
+			fmt.Fprintf(w, "  go: %s\n", m.GoVersion)
+		}
+		if len(m.Deps) > 0 {
+			fmt.Fprintln(w, "  require:")
+			for _, r := range m.Deps {
+				line := strings.TrimSpace(r.Path + " " + r.Version)
+				if r.Indirect {
+					line += " // indirect"
+				}
+				fmt.Fprintf(w, "    %s\n", line)
+			}
+		}
+		if len(m.Replaces) > 0 {
+			fmt.Fprintln(w, "  replace:")
+			for _, r := range m.Replaces {
+				fmt.Fprintf(w, "    %s => %s\n",
+					strings.TrimSpace(r.OldPath+" "+r.OldVersion),
+					strings.TrimSpace(r.NewPath+" "+r.NewVersion))
+			}
+		}
+	}
+}
+
+// moduleJSON はモジュール一覧のJSON出力構造体です
+type moduleJSON struct {
+	Path      string              `json:"path"`
+	Root      string              `json:"root"`
+	Version   string              `json:"version,omitempty"`
+	Vendored  string              `json:"vendored_by,omitempty"`
+	GoVersion string              `json:"go,omitempty"`
+	Requires  []gomod.Requirement `json:"requires,omitempty"`
+	Replaces  []gomod.Replacement `json:"replaces,omitempty"`
+}
+
+// printModulesJSON はモジュール一覧をJSONで出力します
+func printModulesJSON(w io.Writer, mods []gomod.Module) error {
+	out := make([]moduleJSON, 0, len(mods))
+	for _, m := range mods {
+		out = append(out, moduleJSON{
+			Path:      m.Path,
+			Root:      m.Root,
+			Version:   m.Version,
+			Vendored:  m.VendoredBy,
+			GoVersion: m.GoVersion,
+			Requires:  m.Deps,
+			Replaces:  m.Replaces,
+		})
+	}
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return fmt.Errorf("JSONエンコード失敗: %w", err)
+	}
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}
+
+func init() {
+	rootCmd.AddCommand(modulesCmd)
+	modulesCmd.Flags().StringVar(&modulesp.Graph, "graph", "", "依存グラフを出力: deps (依存先) | rdeps (参照元)")
+}
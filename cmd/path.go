@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/meian/rev-callgraph/internal/callgraph"
+	"github.com/meian/rev-callgraph/internal/format"
+	"github.com/meian/rev-callgraph/internal/gomod"
+	"github.com/meian/rev-callgraph/internal/progress"
+	"github.com/meian/rev-callgraph/internal/stats"
+	"github.com/meian/rev-callgraph/internal/symbol"
+	"github.com/spf13/cobra"
+)
+
+// pathp は path サブコマンドのフラグ値を保持します
+var pathp struct {
+	// MaxDepth は逆探索の最大深さ
+	MaxDepth int
+	// Limit は出力する経路の最大数
+	Limit int
+}
+
+var pathCmd = &cobra.Command{
+	Use:   "path <from> <to>",
+	Short: "2つの関数間の呼び出し経路を出力",
+	Long: `<to> の呼び出し元を逆方向に辿って <from> に至る呼び出しの経路を出力します。
+<from> に到達しない分岐は除外し、同じ関数を二度通らない全ての経路 (--limit 指定時は最初の N 件) を --format の形式で出力します。`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := commandContext(cmd)
+		from, to := args[0], args[1]
+		if pathp.Limit < 0 {
+			return fmt.Errorf("--limit は0以上を指定してください: %d", pathp.Limit)
+		}
+		if from == to {
+			return fmt.Errorf("<from> と <to> に同じ関数は指定できません: %s", from)
+		}
+		p, err := format.NewReportPrinter[format.Paths](rootp.Format)
+		if err != nil {
+			return err
+		}
+
+		mods, err := scanWorkspace(ctx, cmd)
+		if err != nil {
+			return err
+		}
+
+		// from と to の定義を確認
+		var toMod *gomod.Module
+		for _, target := range []string{from, to} {
+			f, err := symbol.ParseFunction(target)
+			if err != nil {
+				return fmt.Errorf("targetの分解失敗: %w", err)
+			}
+			mod, def, err := mods.LookupDefinition(ctx, f)
+			if err != nil {
+				return fmt.Errorf("targetの存在確認失敗: %w", err)
+			}
+			if mod == nil && target == from {
+				// from は vendored のコピーにのみ定義されている場合も経路の始点とする
+				progress.Msgf(ctx, "from %s not defined outside vendor directories", from)
+				continue
+			}
+			if mod == nil {
+				return fmt.Errorf("targetが見つかりません: %s", target)
+			}
+			progress.Msgf(ctx, "target %s defined at %s", target, def.Pos)
+			toMod = mod
+		}
+
+		ctx, err = indexWorkspace(ctx)
+		if err != nil {
+			return err
+		}
+
+		g, paths, err := callgraph.CallPaths(ctx, *toMod, from, to, *mods, pathp.Limit, pathp.MaxDepth)
+		if g == nil {
+			return fmt.Errorf("呼び出し元の取得失敗: %w", err)
+		}
+		end := stats.Start(ctx, stats.PhasePrint, rootp.Format)
+		perr := p.Print(format.Paths{Graph: g, Paths: paths})
+		end()
+		if perr != nil {
+			return perr
+		}
+		if err != nil {
+			cmd.SilenceUsage = true
+			return fmt.Errorf("経路の探索が中断されました (%d 件): %w", len(paths), err)
+		}
+		if len(paths) == 0 {
+			fmt.Fprintf(cmd.ErrOrStderr(), "%s から %s への経路は見つかりませんでした\n", from, to)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(pathCmd)
+	pathCmd.Flags().IntVar(&pathp.MaxDepth, "max-depth", 0, "逆探索の最大深さ (0は制限なし)")
+	pathCmd.Flags().IntVar(&pathp.Limit, "limit", 0, "出力する経路の最大数 (0は制限なし)")
+}
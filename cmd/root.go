@@ -1,14 +1,20 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
-	"path/filepath"
+	"os/signal"
+	"runtime"
+	"time"
 
 	"github.com/meian/rev-callgraph/internal/callgraph"
 	"github.com/meian/rev-callgraph/internal/format"
 	"github.com/meian/rev-callgraph/internal/gomod"
+	"github.com/meian/rev-callgraph/internal/graph"
 	"github.com/meian/rev-callgraph/internal/progress"
+	"github.com/meian/rev-callgraph/internal/stats"
 	"github.com/meian/rev-callgraph/internal/symbol"
 	"github.com/spf13/cobra"
 )
@@ -24,30 +30,69 @@ var rootp struct {
 	JSONStyle string
 	// MaxDepth は逆探索の最大深さ
 	MaxDepth int
+	// Shortest は起点 (main, テスト, HTTP ハンドラ) から target への最短の経路のみ出力するかどうか
+	Shortest bool
+	// K は Shortest 指定時に出力する経路の数
+	K int
+	// EntryPoints は target に到達するエントリポイントの一覧を出力するかどうか
+	EntryPoints bool
+	// Cycles は呼び出し元のグラフに含まれる循環の一覧を出力するかどうか
+	Cycles bool
+	// NodeStats は呼び出し元のグラフのノードごとの統計を出力するかどうか
+	NodeStats bool
+	// Tests は target に到達するテスト関数を go test のコマンドラインとして出力するかどうか
+	Tests bool
 	// Progress は進捗を表示するかどうか
 	// デフォルトはfalse
 	Progress bool
+	// PreferModules はモジュールパスが重複した場合に優先するモジュールのルートディレクトリ
+	PreferModules []string
+	// NoCache は解析キャッシュを使わないかどうか
+	NoCache bool
+	// Incremental は git のコミットを記録したインデックスを保存し、変更ファイルのみ再抽出するかどうか
+	Incremental bool
+	// Vendor は vendor ディレクトリに取り込まれたモジュールも解析対象に含めるかどうか
+	Vendor bool
+	// MaxFileSize は解析対象とするファイルサイズの上限 (バイト)
+	// 0の場合は上限なし
+	MaxFileSize int64
+	// Stats は終了時にフェーズごとの所要時間と件数を表示するかどうか
+	Stats bool
+	// CPUProfile は CPU プロファイルの出力先
+	CPUProfile string
+	// MemProfile はメモリプロファイルの出力先
+	MemProfile string
+	// TraceOut は Chrome のトレースイベント形式のトレースの出力先
+	TraceOut string
+	// Jobs はファイル走査・解析・呼び出し元展開の並列数
+	// デフォルトは GOMAXPROCS
+	Jobs int
+	// Timeout はコマンド全体の制限時間
+	// 0の場合は制限なし
+	Timeout time.Duration
 }
 
+// cancelTimeout は --timeout で設定したコンテキストを解放します
+var cancelTimeout context.CancelFunc = func() {}
+
 var rootCmd = &cobra.Command{
 	Use:   "rev-callgraph <target>",
 	Short: "逆方向コールグラフ生成ツール",
 	Long:  `Goコードの逆方向コールグラフを生成するCLIツールです。`,
 	Args:  cobra.ExactArgs(1),
-	RunE: func(cmd *cobra.Command, args []string) error {
-		ctx := cmd.Context()
-		if rootp.Progress {
-			m := progress.NewMessenger(cmd.ErrOrStderr())
-			ctx = progress.WithProgress(ctx, m)
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if rootp.Timeout > 0 {
+			var ctx context.Context
+			ctx, cancelTimeout = context.WithTimeout(cmd.Context(), rootp.Timeout)
+			cmd.SetContext(ctx)
 		}
+		return startInstrumentation(cmd)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := commandContext(cmd)
 		target := args[0]
-		dir := rootp.Dir
-		if dir == "" {
-			dir = filepath.Clean(".")
-		}
-		dir, err := filepath.Abs(dir)
-		if err != nil {
-			return fmt.Errorf("絶対パスの取得失敗: %w", err)
+		if cmd.Flags().Changed("k") && !rootp.Shortest {
+			return fmt.Errorf("--k は --shortest と同時に指定してください")
 		}
 
 		// targetパース
@@ -58,46 +103,237 @@ var rootCmd = &cobra.Command{
 		}
 
 		// ディレクトリ内の全モジュールを検出
-		progress.Msgf(ctx, "scan modules in %s", dir)
-		mods, err := gomod.Scan(ctx, dir)
+		mods, err := scanWorkspace(ctx, cmd)
 		if err != nil {
-			return fmt.Errorf("モジュールスキャン失敗: %w", err)
+			return err
 		}
 
 		// 対象が含まれるモジュールを検出
-		mod, err := mods.FindByFunction(ctx, f)
+		mod, def, err := mods.LookupDefinition(ctx, f)
 		if err != nil {
 			return fmt.Errorf("targetの存在確認失敗: %w", err)
 		}
 		if mod == nil {
 			return fmt.Errorf("targetが見つかりません: %s", target)
 		}
+		progress.Msgf(ctx, "target %s defined at %s", target, def.Pos)
 
-		root, err := callgraph.CallersTree(ctx, *mod, target, *mods, 0, nil, rootp.MaxDepth)
+		// ワークスペース内の識別子インデックスを構築
+		ctx, err = indexWorkspace(ctx)
 		if err != nil {
-			return fmt.Errorf("呼び出し元の取得失敗: %w", err)
+			return err
+		}
+
+		if rootp.Shortest {
+			return printShortest(ctx, cmd, *mod, target, *mods)
+		}
+		if rootp.EntryPoints {
+			return printEntryPoints(ctx, cmd, *mod, target, *mods)
+		}
+
+		if rootp.Cycles {
+			return printCycles(ctx, cmd, *mod, target, *mods)
+		}
+		if rootp.NodeStats {
+			return printNodeStats(ctx, cmd, *mod, target, *mods)
+		}
+		if rootp.Tests {
+			// 探索の前に出力形式を確認する
+			if _, err := format.NewReportPrinter[format.Tests](rootp.Format); err != nil {
+				return err
+			}
 		}
 
-		p, err := format.NewPrinter(rootp.Format, rootp.JSONStyle)
+		root, err := callgraph.CallersGraph(ctx, *mod, target, *mods, rootp.MaxDepth)
+		if root == nil {
+			return fmt.Errorf("呼び出し元の取得失敗: %w", err)
+		}
+		var perr error
+		if rootp.Tests {
+			perr = printTests(ctx, root, *mods)
+		} else {
+			perr = printGraph(ctx, root, rootp.MaxDepth)
+		}
+		if perr != nil {
+			return perr
+		}
 		if err != nil {
-			return err
+			cmd.SilenceUsage = true
+			return fmt.Errorf("呼び出し元の探索が中断されました (未展開 %d ノード): %w", len(root.Truncated()), err)
 		}
-		return p.Print(root)
+		return nil
 	},
 }
 
+// printShortest は起点から target への最短の経路を探索して出力します
+func printShortest(ctx context.Context, cmd *cobra.Command, mod gomod.Module, target string, mods gomod.ModuleMap) error {
+	if rootp.K < 1 {
+		return fmt.Errorf("--k は1以上を指定してください: %d", rootp.K)
+	}
+	p, err := format.NewReportPrinter[format.Paths](rootp.Format)
+	if err != nil {
+		return err
+	}
+	g, paths, err := callgraph.ShortestCallers(ctx, mod, target, mods, rootp.K, rootp.MaxDepth)
+	if g == nil {
+		return fmt.Errorf("呼び出し元の取得失敗: %w", err)
+	}
+	end := stats.Start(ctx, stats.PhasePrint, rootp.Format)
+	perr := p.Print(format.Paths{Graph: g, Paths: paths})
+	end()
+	if perr != nil {
+		return perr
+	}
+	if err != nil {
+		cmd.SilenceUsage = true
+		return fmt.Errorf("最短経路の探索が中断されました (%d 件): %w", len(paths), err)
+	}
+	if len(paths) == 0 {
+		progress.Msgf(ctx, "no entry point reaches %s", target)
+	}
+	return nil
+}
+
+// printEntryPoints は target に到達するエントリポイントを分類して出力します
+func printEntryPoints(ctx context.Context, cmd *cobra.Command, mod gomod.Module, target string, mods gomod.ModuleMap) error {
+	p, err := format.NewReportPrinter[format.EntryPoints](rootp.Format)
+	if err != nil {
+		return err
+	}
+	g, eps, err := callgraph.EntryPoints(ctx, mod, target, mods, rootp.MaxDepth)
+	if g == nil {
+		return fmt.Errorf("呼び出し元の取得失敗: %w", err)
+	}
+	end := stats.Start(ctx, stats.PhasePrint, rootp.Format)
+	perr := p.Print(format.EntryPoints{Graph: g, EntryPoints: eps})
+	end()
+	if perr != nil {
+		return perr
+	}
+	if err != nil {
+		cmd.SilenceUsage = true
+		return fmt.Errorf("呼び出し元の探索が中断されました (未展開 %d ノード): %w", len(g.Truncated()), err)
+	}
+	return nil
+}
+
+// printCycles は target の呼び出し元のグラフに含まれる循環を出力します
+func printCycles(ctx context.Context, cmd *cobra.Command, mod gomod.Module, target string, mods gomod.ModuleMap) error {
+	p, err := format.NewReportPrinter[format.Cycles](rootp.Format)
+	if err != nil {
+		return err
+	}
+	g, err := callgraph.CallersGraph(ctx, mod, target, mods, rootp.MaxDepth)
+	if g == nil {
+		return fmt.Errorf("呼び出し元の取得失敗: %w", err)
+	}
+	cycles := g.Cycles()
+	progress.Msgf(ctx, "%d cycles found in %d nodes", len(cycles), len(g.Nodes))
+	end := stats.Start(ctx, stats.PhasePrint, rootp.Format)
+	perr := p.Print(format.Cycles{Graph: g, Cycles: cycles})
+	end()
+	if perr != nil {
+		return perr
+	}
+	if err != nil {
+		cmd.SilenceUsage = true
+		return fmt.Errorf("呼び出し元の探索が中断されました (未展開 %d ノード): %w", len(g.Truncated()), err)
+	}
+	return nil
+}
+
+// printNodeStats は target の呼び出し元のグラフのノードごとに、呼び出し元の数、到達するエントリポイントの数、深さを出力します
+func printNodeStats(ctx context.Context, cmd *cobra.Command, mod gomod.Module, target string, mods gomod.ModuleMap) error {
+	p, err := format.NewReportPrinter[format.NodeStats](rootp.Format)
+	if err != nil {
+		return err
+	}
+	g, err := callgraph.CallersGraph(ctx, mod, target, mods, rootp.MaxDepth)
+	if g == nil {
+		return fmt.Errorf("呼び出し元の取得失敗: %w", err)
+	}
+	ns := callgraph.NodeStats(g, []string{g.Root}, rootp.MaxDepth)
+	end := stats.Start(ctx, stats.PhasePrint, rootp.Format)
+	perr := p.Print(ns)
+	end()
+	if perr != nil {
+		return perr
+	}
+	if err != nil {
+		cmd.SilenceUsage = true
+		return fmt.Errorf("呼び出し元の探索が中断されました (未展開 %d ノード): %w", len(g.Truncated()), err)
+	}
+	return nil
+}
+
+// printGraph はフラグで指定された形式でグラフを標準出力に出力します
+func printGraph(ctx context.Context, g *graph.Graph, maxDepth int) error {
+	p, err := format.NewPrinter(rootp.Format, rootp.JSONStyle, maxDepth)
+	if err != nil {
+		return err
+	}
+	defer stats.Start(ctx, stats.PhasePrint, rootp.Format)()
+	return p.Print(g)
+}
+
+// printTests はグラフに含まれるテスト関数をパッケージディレクトリごとにまとめ、
+// フラグで指定された形式で標準出力に出力します
+func printTests(ctx context.Context, g *graph.Graph, mods gomod.ModuleMap) error {
+	p, err := format.NewReportPrinter[format.Tests](rootp.Format)
+	if err != nil {
+		return err
+	}
+	dir, err := workspaceDir()
+	if err != nil {
+		return err
+	}
+	pkgs := callgraph.AffectedTests(g, mods, dir)
+	progress.Msgf(ctx, "%d packages have affected tests", len(pkgs))
+	defer stats.Start(ctx, stats.PhasePrint, rootp.Format)()
+	return p.Print(pkgs)
+}
+
 // Execute はCLIを実行します
+// SIGINT を受け取るとコンテキストをキャンセルし、それまでの結果を出力して終了します
+// 計測を有効にした場合は、コマンドの成否に関わらず終了時に結果を出力します
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Println(err)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	err := rootCmd.ExecuteContext(ctx)
+	stop()
+	cancelTimeout()
+	if ferr := finishInstrumentation(os.Stderr); ferr != nil {
+		err = errors.Join(err, ferr)
+	}
+	if err != nil {
+		// 標準出力には途中までの結果が出力されている場合があるため、エラーは標準エラー出力に書く
+		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
 }
 
 func init() {
-	rootCmd.Flags().StringVar(&rootp.Dir, "dir", "", "解析するワークスペースのルートディレクトリ")
-	rootCmd.Flags().StringVar(&rootp.Format, "format", "tree", "出力形式: json|tree|dot")
-	rootCmd.Flags().StringVar(&rootp.JSONStyle, "json-style", "nested", "json出力スタイル: nested|edges")
+	rootCmd.PersistentFlags().StringVar(&rootp.Dir, "dir", "", "解析するワークスペースのルートディレクトリ")
+	rootCmd.PersistentFlags().StringVar(&rootp.Format, "format", "tree", "出力形式: json|tree|dot")
+	rootCmd.PersistentFlags().StringVar(&rootp.JSONStyle, "json-style", "nested", "json出力スタイル: nested|edges")
 	rootCmd.Flags().IntVar(&rootp.MaxDepth, "max-depth", 0, "逆探索の最大深さ (0は制限なし)")
-	rootCmd.Flags().BoolVar(&rootp.Progress, "progress", false, "進捗を表示するかどうか")
+	rootCmd.Flags().BoolVar(&rootp.Shortest, "shortest", false, "起点 (main, テスト, HTTP ハンドラ) から target への最短の経路のみ出力する")
+	rootCmd.Flags().BoolVar(&rootp.EntryPoints, "entrypoints", false, "target に到達するエントリポイント (main, init, テスト, HTTP ハンドラ, 公開 API, 呼び出し元なし) を分類して出力する")
+	rootCmd.Flags().BoolVar(&rootp.Cycles, "cycles", false, "呼び出し元のグラフに含まれる循環 (再帰・相互再帰) ごとにメンバーと循環を構成する呼び出し箇所を出力する")
+	rootCmd.Flags().BoolVar(&rootp.NodeStats, "stats-nodes", false, "呼び出し元のグラフのノードごとに直接・推移的な呼び出し元の数、到達するエントリポイントの数、target からの深さを出力する")
+	rootCmd.Flags().BoolVar(&rootp.Tests, "tests", false, "target に到達するテスト関数をパッケージごとに go test のコマンドラインとして出力する (--format json も可)")
+	rootCmd.Flags().IntVar(&rootp.K, "k", 1, "--shortest で出力する経路の数 (target に近い起点から順に)")
+	// 出力の種類を切り替えるフラグは同時に指定できない
+	rootCmd.MarkFlagsMutuallyExclusive("shortest", "entrypoints", "cycles", "stats-nodes", "tests")
+	rootCmd.PersistentFlags().BoolVar(&rootp.Progress, "progress", false, "進捗を表示するかどうか")
+	rootCmd.PersistentFlags().BoolVar(&rootp.NoCache, "no-cache", false, "解析キャッシュを使わずに全てのファイルを解析する")
+	rootCmd.PersistentFlags().BoolVar(&rootp.Incremental, "incremental", false, "git のコミットを記録した識別子インデックスをキャッシュに保存し、以降は変更されたファイルのみ再抽出する")
+	rootCmd.PersistentFlags().BoolVar(&rootp.Vendor, "vendor", false, "vendor ディレクトリに取り込まれたモジュールも解析対象に含めるかどうか")
+	rootCmd.PersistentFlags().Int64Var(&rootp.MaxFileSize, "max-file-size", 10<<20, "解析対象とするファイルサイズの上限 (バイト, 0は制限なし)。超えるファイルは警告を出してスキップする")
+	rootCmd.PersistentFlags().BoolVar(&rootp.Stats, "stats", false, "終了時にフェーズごとの所要時間と件数を標準エラー出力に表示する")
+	rootCmd.PersistentFlags().StringVar(&rootp.CPUProfile, "cpuprofile", "", "CPU プロファイル (pprof 形式) の出力先")
+	rootCmd.PersistentFlags().StringVar(&rootp.MemProfile, "memprofile", "", "メモリプロファイル (pprof 形式) の出力先")
+	rootCmd.PersistentFlags().StringVar(&rootp.TraceOut, "trace-out", "", "フェーズ・探索対象ごとの区間を Chrome のトレースイベント形式 (JSON) で出力する先")
+	rootCmd.PersistentFlags().DurationVar(&rootp.Timeout, "timeout", 0, "コマンド全体の制限時間 (例: 30s, 0は制限なし)。超えた場合は途中までの結果を出力して失敗する (deadcode と targets を省略した top は結果を出力せずに失敗する)")
+	rootCmd.PersistentFlags().IntVar(&rootp.Jobs, "jobs", runtime.GOMAXPROCS(0), "ファイル走査・解析・呼び出し元展開の並列数 (1で逐次実行)")
+	rootCmd.PersistentFlags().StringSliceVar(&rootp.PreferModules, "prefer-module", nil, "モジュールパスが重複した場合に優先するモジュールのルートディレクトリ")
 }
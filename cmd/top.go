@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/meian/rev-callgraph/internal/callgraph"
+	"github.com/meian/rev-callgraph/internal/format"
+	"github.com/meian/rev-callgraph/internal/progress"
+	"github.com/meian/rev-callgraph/internal/stats"
+	"github.com/meian/rev-callgraph/internal/symbol"
+	"github.com/spf13/cobra"
+)
+
+// topp は top サブコマンドのフラグ値を保持します
+var topp struct {
+	// By は順位付けに使う指標
+	By string
+	// Limit は出力する件数
+	Limit int
+	// MaxDepth は逆探索の最大深さ
+	MaxDepth int
+}
+
+var topCmd = &cobra.Command{
+	Use:   "top [targets...]",
+	Short: "呼び出し元の多い関数 (ホットスポット) を順位付けして出力",
+	Long: `targets の呼び出し元をまとめて辿ったグラフ (targets を省略した場合はワークスペース全体のコールグラフ) の
+関数/メソッドごとに直接・推移的な呼び出し元の数と到達するエントリポイントの数を求め、
+--by の指標の降順に上位 --limit 件を --format の形式で出力します。`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := commandContext(cmd)
+		if topp.Limit < 0 {
+			return fmt.Errorf("--limit は0以上を指定してください: %d", topp.Limit)
+		}
+		p, err := format.NewReportPrinter[format.NodeStats](rootp.Format)
+		if err != nil {
+			return err
+		}
+		var targets []symbol.Function
+		for _, arg := range args {
+			f, err := symbol.ParseFunction(arg)
+			if err != nil {
+				return fmt.Errorf("targetの分解失敗: %w", err)
+			}
+			targets = append(targets, f)
+		}
+		mods, err := scanWorkspace(ctx, cmd)
+		if err != nil {
+			return err
+		}
+		for _, f := range targets {
+			mod, def, err := mods.LookupDefinition(ctx, f)
+			if err != nil {
+				return fmt.Errorf("targetの存在確認失敗: %w", err)
+			}
+			if mod == nil {
+				return fmt.Errorf("targetが見つかりません: %s", f)
+			}
+			progress.Msgf(ctx, "target %s defined at %s", f, def.Pos)
+		}
+		if len(targets) > 0 {
+			ctx, err = indexWorkspace(ctx)
+			if err != nil {
+				return err
+			}
+		}
+
+		g, hot, err := callgraph.Hotspots(ctx, targets, *mods, callgraph.HotspotMetric(topp.By), topp.Limit, topp.MaxDepth)
+		if g == nil && ctx.Err() != nil {
+			cmd.SilenceUsage = true
+			return fmt.Errorf("ホットスポットの探索が中断されました (途中までの結果は出力しません): %w", err)
+		}
+		if g == nil {
+			return fmt.Errorf("ホットスポットの取得失敗: %w", err)
+		}
+		end := stats.Start(ctx, stats.PhasePrint, rootp.Format)
+		perr := p.Print(hot)
+		end()
+		if perr != nil {
+			return perr
+		}
+		if err != nil {
+			cmd.SilenceUsage = true
+			return fmt.Errorf("呼び出し元の探索が中断されました (未展開 %d ノード): %w", len(g.Truncated()), err)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(topCmd)
+	topCmd.Flags().StringVar(&topp.By, "by", string(callgraph.MetricTransitive), "順位付けの指標: callers|transitive|entrypoints")
+	topCmd.Flags().IntVar(&topp.Limit, "limit", 20, "出力する件数 (0は全件)")
+	topCmd.Flags().IntVar(&topp.MaxDepth, "max-depth", 0, "targets からの逆探索の最大深さ (0は制限なし)")
+}
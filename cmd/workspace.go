@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/meian/rev-callgraph/internal/cache"
+	"github.com/meian/rev-callgraph/internal/diag"
+	"github.com/meian/rev-callgraph/internal/gomod"
+	"github.com/meian/rev-callgraph/internal/index"
+	"github.com/meian/rev-callgraph/internal/parallel"
+	"github.com/meian/rev-callgraph/internal/progress"
+	"github.com/meian/rev-callgraph/internal/srcfile"
+	"github.com/meian/rev-callgraph/internal/stats"
+	"github.com/spf13/cobra"
+)
+
+// commandContext はフラグに応じて進捗出力・診断出力・並列数・ファイルサイズ上限・解析キャッシュを設定したコンテキストを返します
+// キャッシュディレクトリが利用できない場合はキャッシュなしで続行します
+func commandContext(cmd *cobra.Command) context.Context {
+	ctx := cmd.Context()
+	if rootp.Progress {
+		m := progress.NewMessenger(cmd.ErrOrStderr())
+		ctx = progress.WithProgress(ctx, m)
+	}
+	ctx = diag.WithReporter(ctx, diag.NewReporter(cmd.ErrOrStderr()))
+	ctx = parallel.WithJobs(ctx, rootp.Jobs)
+	ctx = srcfile.WithMaxSize(ctx, rootp.MaxFileSize)
+	if !rootp.NoCache {
+		s, err := openCache()
+		if err != nil {
+			progress.Msgf(ctx, "cache disabled: %v", err)
+			return ctx
+		}
+		progress.Msgf(ctx, "use cache in %s", s.Dir())
+		ctx = cache.WithStore(ctx, s)
+	}
+	return ctx
+}
+
+// openCache はユーザーキャッシュディレクトリ配下の解析キャッシュを開きます
+func openCache() (*cache.Store, error) {
+	dir, err := cache.DefaultDir()
+	if err != nil {
+		return nil, err
+	}
+	return cache.Open(dir)
+}
+
+// indexDir は --incremental で保存するインデックスのディレクトリを返します
+func indexDir(s *cache.Store) string {
+	return filepath.Join(s.Dir(), "index")
+}
+
+// workspaceDir は解析するワークスペースのルートディレクトリを絶対パスで返します
+func workspaceDir() (string, error) {
+	dir := rootp.Dir
+	if dir == "" {
+		dir = filepath.Clean(".")
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("絶対パスの取得失敗: %w", err)
+	}
+	return dir, nil
+}
+
+// scanWorkspace はワークスペース内の全モジュールを検出します
+// モジュールパスの重複があれば警告を出力します
+func scanWorkspace(ctx context.Context, cmd *cobra.Command) (*gomod.ModuleMap, error) {
+	dir, err := workspaceDir()
+	if err != nil {
+		return nil, err
+	}
+	progress.Msgf(ctx, "scan modules in %s", dir)
+	end := stats.Start(ctx, stats.PhaseScan, dir)
+	mods, err := gomod.Scan(ctx, dir, gomod.ScanOptions{Prefer: rootp.PreferModules, Vendor: rootp.Vendor})
+	end()
+	if err != nil {
+		return nil, fmt.Errorf("モジュールスキャン失敗: %w", err)
+	}
+	printConflicts(cmd.ErrOrStderr(), mods.Conflicts())
+	return mods, nil
+}
+
+// indexWorkspace はワークスペース内の識別子インデックスを構築し、コンテキストに設定します
+// --incremental 指定時は保存したインデックスを git の変更ファイルのみ更新して使います
+func indexWorkspace(ctx context.Context) (context.Context, error) {
+	dir, err := workspaceDir()
+	if err != nil {
+		return nil, err
+	}
+	end := stats.Start(ctx, stats.PhaseIndex, dir)
+	var idx *index.Index
+	if s := cache.FromContext(ctx); rootp.Incremental && s != nil {
+		idx, err = index.Incremental(ctx, dir, indexDir(s))
+	} else {
+		if rootp.Incremental {
+			progress.Msgf(ctx, "incremental index disabled: cache is not available")
+		}
+		idx, err = index.Build(ctx, dir)
+	}
+	end()
+	if err != nil {
+		return nil, fmt.Errorf("インデックス構築失敗: %w", err)
+	}
+	return index.WithIndex(ctx, idx), nil
+}
+
+// printConflicts はモジュールパスの重複を警告として出力します
+func printConflicts(w io.Writer, conflicts []gomod.Conflict) {
+	for _, c := range conflicts {
+		fmt.Fprintf(w, "警告: モジュールパス %s が複数のディレクトリで宣言されています (%s により %s を採用)\n", c.Path, c.Reason, c.Chosen)
+		for _, r := range c.Roots {
+			fmt.Fprintf(w, "  - %s\n", r)
+		}
+	}
+}
@@ -3,14 +3,38 @@ package main_test
 import (
 	"bytes"
 	"encoding/json"
+	"io/fs"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 	"testing"
 )
 
+// command は rev-callgraph CLI を go run で実行するコマンドを返します
+// 解析キャッシュが開発者のキャッシュディレクトリに書き込まれないように、ユーザーのキャッシュディレクトリを cacheDir に向けます
+// go コマンド自身のビルドキャッシュ、モジュールキャッシュ、設定ファイルは元の場所を使います
+func command(t *testing.T, cacheDir string, args ...string) *exec.Cmd {
+	t.Helper()
+	out, err := exec.Command("go", "env", "GOCACHE", "GOMODCACHE", "GOPATH", "GOENV").Output()
+	if err != nil {
+		t.Fatalf("go env 実行失敗: %v", err)
+	}
+	goenv := strings.Split(strings.TrimSpace(string(out)), "\n")
+	if len(goenv) != 4 {
+		t.Fatalf("go env の出力が不正です: %q", out)
+	}
+	cmd := exec.Command("go", append([]string{"run", "."}, args...)...)
+	cmd.Env = append(os.Environ(),
+		"GOCACHE="+goenv[0], "GOMODCACHE="+goenv[1], "GOPATH="+goenv[2], "GOENV="+goenv[3],
+		"XDG_CACHE_HOME="+cacheDir, "HOME="+cacheDir, "LocalAppData="+cacheDir)
+	return cmd
+}
+
 func TestE2E(t *testing.T) {
 	// rev-callgraph CLIをgo runで実行
-	cmd := exec.Command("go", "run", ".", "github.com/meian/rev-callgraph/testdata/foo.Target", "--dir", "testdata", "--format", "json", "--json-style", "edges")
+	cacheDir := t.TempDir()
+	cmd := command(t, cacheDir, "github.com/meian/rev-callgraph/testdata/foo.Target", "--dir", "testdata", "--format", "json", "--json-style", "edges")
 	var out bytes.Buffer
 	cmd.Stdout = &out
 	cmd.Stderr = &out
@@ -26,8 +50,13 @@ func TestE2E(t *testing.T) {
 	jsonText := output[idx:]
 	// JSON構造をパース
 	var result struct {
-		Root  string              `json:"root"`
-		Edges []map[string]string `json:"edges"`
+		Root  string `json:"root"`
+		Edges []struct {
+			Caller string   `json:"caller"`
+			Callee string   `json:"callee"`
+			Count  int      `json:"count"`
+			Sites  []string `json:"sites"`
+		} `json:"edges"`
 	}
 	if err := json.Unmarshal([]byte(jsonText), &result); err != nil {
 		t.Fatalf("JSONパース失敗: %v, raw: %s", err, jsonText)
@@ -39,12 +68,55 @@ func TestE2E(t *testing.T) {
 	// 呼び出し元にexample.com/bar.Callerが含まれること
 	found := false
 	for _, e := range result.Edges {
-		if e["caller"] == "github.com/meian/rev-callgraph/testdata/bar.Caller" && e["callee"] == "github.com/meian/rev-callgraph/testdata/foo.Target" {
+		if e.Caller == "github.com/meian/rev-callgraph/testdata/bar.Caller" && e.Callee == "github.com/meian/rev-callgraph/testdata/foo.Target" {
 			found = true
+			// エッジは呼び出し箇所の数と呼び出し箇所を持つ
+			if e.Count != 1 || len(e.Sites) != 1 || !strings.HasSuffix(e.Sites[0], "bar.go:11:2") {
+				t.Errorf("Unexpected sites: count=%d, sites=%v", e.Count, e.Sites)
+			}
 			break
 		}
 	}
 	if !found {
 		t.Errorf("Expected edge not found: bar.Caller -> foo.Target, got edges: %#v", result.Edges)
 	}
+	// 解析キャッシュは一時ディレクトリ (OS により配下の階層は異なる) に書き込まれる
+	cached := false
+	filepath.WalkDir(cacheDir, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && d.IsDir() && d.Name() == "rev-callgraph" {
+			cached = true
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	if !cached {
+		t.Errorf("解析キャッシュが一時ディレクトリに見つかりません: %s", cacheDir)
+	}
+}
+
+func TestE2E_FlagConflicts(t *testing.T) {
+	// 出力の種類を切り替えるフラグの組み合わせと --shortest なしの --k はエラーとする
+	for _, args := range [][]string{
+		{"--shortest", "--cycles"},
+		{"--entrypoints", "--tests"},
+		{"--stats-nodes", "--shortest"},
+		{"--k", "2"},
+	} {
+		cmd := command(t, t.TempDir(), append([]string{"github.com/meian/rev-callgraph/testdata/foo.Target", "--dir", "testdata"}, args...)...)
+		var out bytes.Buffer
+		cmd.Stdout = &out
+		cmd.Stderr = &out
+		if err := cmd.Run(); err == nil {
+			t.Errorf("%v: エラーが期待されますが成功しました, 出力: %s", args, out.String())
+		}
+	}
+
+	// モジュール一覧は依存グラフ以外を dot 形式で出力できない
+	cmd := command(t, t.TempDir(), "modules", "--dir", "testdata", "--format", "dot")
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := cmd.Run(); err == nil {
+		t.Errorf("modules --format dot: エラーが期待されますが成功しました, 出力: %s", out.String())
+	}
 }
@@ -3,15 +3,19 @@ package astquery
 import (
 	"context"
 	"fmt"
-	"go/ast"
-	"go/parser"
 	"go/token"
 	"path/filepath"
+	"slices"
 	"strings"
 
-	"github.com/meian/rev-callgraph/internal/contextutil"
+	"github.com/meian/rev-callgraph/internal/cache"
+	"github.com/meian/rev-callgraph/internal/diag"
 	"github.com/meian/rev-callgraph/internal/gomod"
+	"github.com/meian/rev-callgraph/internal/index"
+	"github.com/meian/rev-callgraph/internal/parallel"
 	"github.com/meian/rev-callgraph/internal/progress"
+	"github.com/meian/rev-callgraph/internal/stats"
+	"github.com/meian/rev-callgraph/internal/summary"
 	"github.com/meian/rev-callgraph/internal/symbol"
 )
 
@@ -20,15 +24,16 @@ func determinePkgPath(filePath string, mm gomod.ModuleMap) string {
 	best := ""
 	root := ""
 	// 対象モジュールを走査
-	// ルートディレクトリが深いものから返されるので、最初に見つかったものを選択
+	// ファイルを含むモジュールのうちルートディレクトリが最も深いものを選択
 	for _, m := range mm.Iter {
 		rel, err := filepath.Rel(m.Root, filepath.Dir(filePath))
 		if err != nil || strings.HasPrefix(rel, "..") {
 			continue
 		}
-		best = m.Path
-		root = m.Root
-		break
+		if len(m.Root) > len(root) {
+			best = m.Path
+			root = m.Root
+		}
 	}
 	if best == "" {
 		return filepath.Base(filepath.Dir(filePath))
@@ -40,118 +45,186 @@ func determinePkgPath(filePath string, mm gomod.ModuleMap) string {
 	return best + "/" + filepath.ToSlash(relPath)
 }
 
-// ExtractCallers は target を呼び出す関数/メソッドのリストを返します。
+// Caller は target を呼び出している関数/メソッドと、その呼び出し箇所を表します
+type Caller struct {
+	symbol.Function
+	// Sites は呼び出し式の位置 (files の順、ファイル内では出現順)
+	Sites []token.Position
+}
+
+// Count は呼び出し箇所の数を返します
+func (c Caller) Count() int {
+	return len(c.Sites)
+}
+
+// ExtractCallers は target を呼び出す関数/メソッドと呼び出し箇所のリストを返します。
+// 同じ関数/メソッドからの複数の呼び出しは1つにまとめ、呼び出し元の名前の順に返します。
 // target の書式は "pkg.Func" または "pkg.Type#Method" です。
-func ExtractCallers(ctx context.Context, target string, files []string, modules gomod.ModuleMap) ([]symbol.Function, error) {
+// コンテキストに index.Index が設定されている場合は、識別子が出現しないファイルや関数の解析を省略します。
+// ファイルの解析結果はコンテキストに cache.Store が設定されていればキャッシュされます。
+// 解析できないファイルは診断を出力して除外します。
+func ExtractCallers(ctx context.Context, target string, files []string, modules gomod.ModuleMap) ([]Caller, error) {
 	progress.Msgf(ctx, "extract callers for %s", target)
-	var callers []symbol.Function
-	for _, file := range files {
-		if contextutil.IsCanceledOrTimedOut(ctx) {
-			return nil, ctx.Err()
+	defer stats.Start(ctx, stats.PhaseParse, target)()
+	sites := make(map[symbol.Function][]token.Position)
+	idx := index.FromContext(ctx)
+	base := CallIdent(target)
+	dotTarget := strings.ReplaceAll(target, "#", ".")
+
+	// ファイルの読み込みとパースは並列に行い、呼び出しの照合はファイル順に行う
+	type parsed struct {
+		sf  *summary.File
+		occ []index.Occurrence
+	}
+	results, errs, err := parallel.Map(ctx, files, func(file string) (parsed, error) {
+		// インデックスで識別子の出現位置を確認し、出現しないファイルはパースしない
+		var occ []index.Occurrence
+		if idx != nil && idx.Covers(filepath.Dir(file)) {
+			occ = idx.InFile(file, base)
+			if len(occ) == 0 {
+				return parsed{}, nil
+			}
 		}
-		// ファイルパース
-		fset := token.NewFileSet()
-		node, err := parser.ParseFile(fset, file, nil, parser.ParseComments)
+		sf, err := cache.Load(ctx, file)
 		if err != nil {
-			return nil, fmt.Errorf("ASTパース失敗 %s: %w", file, err)
+			return parsed{}, fmt.Errorf("ASTパース失敗 %s: %w", file, err)
 		}
+		return parsed{sf: sf, occ: occ}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
 
-		// インポートマップ: エイリアスまたはパッケージ名 -> モジュールパス
-		importMap := make(map[string]string)
-		for _, imp := range node.Imports {
-			impPath := strings.Trim(imp.Path.Value, `"`)
-			alias := ""
-			if imp.Name != nil && imp.Name.Name != "_" {
-				alias = imp.Name.Name
-			} else {
-				parts := strings.Split(impPath, "/")
-				alias = parts[len(parts)-1]
-			}
-			importMap[alias] = impPath
+	for i, file := range files {
+		if errs[i] != nil {
+			// 解析できないファイルは診断を出力して照合から除外する
+			diag.Warnf(ctx, file, "呼び出し元の抽出をスキップしました: %v", errs[i])
+			continue
 		}
+		sf, occ := results[i].sf, results[i].occ
+		if sf == nil {
+			continue
+		}
+
+		// インポートマップ: エイリアスまたはパッケージ名 -> モジュールパス
+		importMap := sf.ImportMap()
 
 		// ファイルのモジュールパスを決定
 		pkgPath := determinePkgPath(file, modules)
 
 		// 関数/メソッド宣言ごとにボディ内を探索
-		for _, decl := range node.Decls {
-			fn, ok := decl.(*ast.FuncDecl)
-			if !ok || fn.Body == nil {
+		for _, fn := range sf.Funcs {
+			if !fn.HasBody {
+				continue
+			}
+			// 識別子が出現しない関数は走査しない
+			if occ != nil && !containsOffset(occ, fn.BodyStart, fn.BodyEnd) {
 				continue
 			}
 
 			// 呼び出し元の名前を構築
-			var callerName string
-			if fn.Recv != nil && len(fn.Recv.List) > 0 {
-				// メソッド: レシーバ型を抽出
-				recvType := fn.Recv.List[0].Type
-				var typeName string
-				switch expr := recvType.(type) {
-				case *ast.StarExpr:
-					if ident, ok := expr.X.(*ast.Ident); ok {
-						typeName = ident.Name
-					}
-				case *ast.Ident:
-					typeName = expr.Name
-				}
-				callerName = fmt.Sprintf("%s.%s#%s", pkgPath, typeName, fn.Name.Name)
-			} else {
-				// 関数
-				callerName = fmt.Sprintf("%s.%s", pkgPath, fn.Name.Name)
-			}
+			caller := symbol.Function{PkgPath: pkgPath, TypeName: fn.Recv, Name: fn.Name}
 
-			// AST走査で CallExpr を検出
-			ast.Inspect(fn.Body, func(n ast.Node) bool {
-				ce, ok := n.(*ast.CallExpr)
-				if !ok {
-					return true
+			// 呼び出し箇所の関数部分を文字列化して比較
+			for _, call := range fn.Calls {
+				site := token.Position{
+					Filename: file, Offset: call.Pos.Offset, Line: call.Pos.Line, Column: call.Pos.Column,
 				}
-
-				// 呼び出し式の関数部分を文字列化して比較
-				var name string
-				switch fun := ce.Fun.(type) {
-				case *ast.SelectorExpr:
-					if pkgIdent, ok := fun.X.(*ast.Ident); ok {
-						if impPath, exists := importMap[pkgIdent.Name]; exists {
-							name = fmt.Sprintf("%s.%s", impPath, fun.Sel.Name)
-						} else if fun.Sel.Name == baseName(target) {
-							// ローカル変数を通じたメソッド呼び出しをターゲットにマッチ
-							name = strings.ReplaceAll(target, "#", ".")
-						} else {
-							name = fmt.Sprintf("%s.%s", pkgPath, fun.Sel.Name)
-						}
-					} else {
-						if fun.Sel.Name == baseName(target) {
-							// ネストされたセレクタによるターゲットメソッド呼び出し
-							name = strings.ReplaceAll(target, "#", ".")
-						} else {
-							name = fmt.Sprintf("%s.%s", pkgPath, fun.Sel.Name)
-						}
-					}
-					if name == strings.ReplaceAll(target, "#", ".") {
-						if fnSym, err := symbol.ParseFunction(callerName); err == nil {
-							callers = append(callers, fnSym)
-						}
-					}
-				case *ast.Ident:
-					if fun.Name == baseName(target) {
-						if fnSym, err := symbol.ParseFunction(callerName); err == nil {
-							callers = append(callers, fnSym)
-						}
+				if !call.Selector {
+					if call.Name == base {
+						sites[caller] = append(sites[caller], site)
 					}
+					continue
 				}
-				return true
-			})
+				var name string
+				if impPath, exists := importMap[call.X]; call.X != "" && exists {
+					name = impPath + "." + call.Name
+				} else if call.Name == base {
+					// ローカル変数やネストされたセレクタを通じたメソッド呼び出しをターゲットにマッチ
+					name = dotTarget
+				} else {
+					name = pkgPath + "." + call.Name
+				}
+				if name == dotTarget {
+					sites[caller] = append(sites[caller], site)
+				}
+			}
 		}
 	}
+
+	callers := make([]Caller, 0, len(sites))
+	for f, ss := range sites {
+		callers = append(callers, Caller{Function: f, Sites: ss})
+	}
+	slices.SortFunc(callers, func(a, b Caller) int {
+		return strings.Compare(a.String(), b.String())
+	})
 	for _, c := range callers {
-		progress.Msgf(ctx, "  caller: %s", c)
+		progress.Msgf(ctx, "  caller: %s (%d calls)", c, c.Count())
 	}
 	return callers, nil
 }
 
-// baseName は target から関数/メソッド名部分を抽出します
-func baseName(target string) string {
+// ExtractImporters は pkg をインポートしているパッケージのパス一覧を返します。
+// 返されるパッケージは重複を除いて辞書順に並びます。pkg 自身 (外部テストパッケージ等) は含みません。
+// ファイルの解析は並列に行い、解析できないファイルは診断を出力して除外します。
+func ExtractImporters(ctx context.Context, pkg string, files []string, modules gomod.ModuleMap) ([]string, error) {
+	defer stats.Start(ctx, stats.PhaseParse, pkg)()
+	progress.Msgf(ctx, "extract importers for %s", pkg)
+
+	// ファイルの読み込みとパースは並列に行い、インポートの照合はファイル順に行う
+	results, errs, err := parallel.Map(ctx, files, func(file string) (*summary.File, error) {
+		sf, err := cache.Load(ctx, file)
+		if err != nil {
+			return nil, fmt.Errorf("ASTパース失敗 %s: %w", file, err)
+		}
+		return sf, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]struct{})
+	var importers []string
+	for i, file := range files {
+		if errs[i] != nil {
+			// 解析できないファイルは診断を出力して照合から除外する
+			diag.Warnf(ctx, file, "インポート元の抽出をスキップしました: %v", errs[i])
+			continue
+		}
+		if !slices.ContainsFunc(results[i].Imports, func(imp summary.Import) bool {
+			return imp.Path == pkg
+		}) {
+			continue
+		}
+		pkgPath := determinePkgPath(file, modules)
+		if pkgPath == pkg {
+			continue
+		}
+		if _, ok := seen[pkgPath]; ok {
+			continue
+		}
+		seen[pkgPath] = struct{}{}
+		importers = append(importers, pkgPath)
+	}
+	slices.Sort(importers)
+	for _, i := range importers {
+		progress.Msgf(ctx, "  importer: %s", i)
+	}
+	return importers, nil
+}
+
+// containsOffset は occ のいずれかが [start, end) の範囲にあるかを判定します
+func containsOffset(occ []index.Occurrence, start, end int) bool {
+	return slices.ContainsFunc(occ, func(o index.Occurrence) bool {
+		return start <= o.Offset && o.Offset < end
+	})
+}
+
+// CallIdent は target から関数/メソッド名部分を抽出します
+// ExtractCallers が呼び出しとして照合するのは、関数部分の末尾の識別子 (f() の f, x.f() の f) が
+// この名前と一致するものだけのため、前段のファイルの絞り込みはこの識別子の出現で行います
+func CallIdent(target string) string {
 	if idx := strings.LastIndexAny(target, ".#"); idx >= 0 {
 		return target[idx+1:]
 	}
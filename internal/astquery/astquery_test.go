@@ -1,13 +1,17 @@
 package astquery
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"os"
 	"path/filepath"
 	"testing"
 
+	"github.com/meian/rev-callgraph/internal/diag"
+	"github.com/meian/rev-callgraph/internal/git"
 	"github.com/meian/rev-callgraph/internal/gomod"
+	"github.com/meian/rev-callgraph/internal/symbol"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -81,3 +85,156 @@ func targetFunc() {
 	// targetFunc を呼び出しているのは TestFunction なので、呼び出し元が1つ見つかるはず
 	assert.Len(t, callers, 1, "呼び出し元が見つかりませんでした")
 }
+
+func TestExtractCallers_Aggregate(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test.go")
+	require.NoError(t, os.WriteFile(testFile, []byte(`package test
+
+func Zeta() {
+	targetFunc()
+	targetFunc()
+	targetFunc()
+}
+
+func Alpha() { targetFunc() }
+
+func targetFunc() {}
+`), 0644))
+
+	modules := gomod.NewModuleMap(map[string]gomod.Module{
+		"test": {
+			Path: "test",
+			Root: tmpDir,
+		},
+	})
+
+	callers, err := ExtractCallers(context.Background(), "test.targetFunc", []string{testFile}, *modules)
+	require.NoError(t, err)
+
+	// 同じ呼び出し元は1つにまとめ、呼び出し元の名前の順に並べる
+	require.Len(t, callers, 2)
+	assert.Equal(t, "test.Alpha", callers[0].String())
+	assert.Equal(t, 1, callers[0].Count())
+	assert.Equal(t, "test.Zeta", callers[1].String())
+	assert.Equal(t, 3, callers[1].Count())
+	var lines []int
+	for _, s := range callers[1].Sites {
+		assert.Equal(t, testFile, s.Filename)
+		lines = append(lines, s.Line)
+	}
+	assert.Equal(t, []int{4, 5, 6}, lines)
+}
+
+func TestExtractCallers_SyntaxError(t *testing.T) {
+	tmpDir := t.TempDir()
+	valid := filepath.Join(tmpDir, "valid.go")
+	require.NoError(t, os.WriteFile(valid, []byte("package test\n\nfunc Caller() { targetFunc() }\n\nfunc targetFunc() {}\n"), 0644))
+	broken := filepath.Join(tmpDir, "broken.go")
+	require.NoError(t, os.WriteFile(broken, []byte("package test\n\nfunc Broken() { targetFunc(\n"), 0644))
+	modules := gomod.NewModuleMap(map[string]gomod.Module{
+		"test": {Path: "test", Root: tmpDir},
+	})
+
+	var out bytes.Buffer
+	ctx := diag.WithReporter(context.Background(), diag.NewReporter(&out))
+
+	// 構文エラーのあるファイルは警告を出して除外し、他のファイルの照合は続行する
+	callers, err := ExtractCallers(ctx, "test.targetFunc", []string{broken, valid}, *modules)
+	require.NoError(t, err)
+	require.Len(t, callers, 1)
+	assert.Equal(t, "test.Caller", callers[0].String())
+	assert.Contains(t, out.String(), broken)
+
+	out.Reset()
+	funcs, err := ChangedFunctions(ctx, map[string][]git.LineRange{
+		broken: {{Start: 1, End: 3}},
+		valid:  {{Start: 3, End: 3}},
+	}, *modules)
+	require.NoError(t, err)
+	assert.Equal(t, []symbol.Function{{PkgPath: "test", Name: "Caller"}}, funcs)
+	assert.Contains(t, out.String(), broken)
+}
+
+func TestChangedFunctions(t *testing.T) {
+	tmpDir := t.TempDir()
+	file := filepath.Join(tmpDir, "a.go")
+	require.NoError(t, os.WriteFile(file, []byte(`package test
+
+// A のドキュメント
+func A() {
+	_ = 1
+}
+
+func B() {
+	_ = 2
+}
+
+func C() {}
+`), 0644))
+	modules := gomod.NewModuleMap(map[string]gomod.Module{
+		"test": {Path: "test", Root: tmpDir},
+	})
+	ctx := context.Background()
+
+	// 関数名の行からボディの終わりの括弧の行までを宣言の範囲とし、ドキュメントコメントや存在しないファイルは無視する
+	funcs, err := ChangedFunctions(ctx, map[string][]git.LineRange{
+		file:                                {{Start: 3, End: 3}, {Start: 6, End: 6}, {Start: 10, End: 11}},
+		filepath.Join(tmpDir, "removed.go"): {{Start: 1, End: 1}},
+	}, *modules)
+	require.NoError(t, err)
+	assert.Equal(t, []symbol.Function{{PkgPath: "test", Name: "A"}, {PkgPath: "test", Name: "B"}}, funcs)
+}
+
+func TestExtractImporters(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(tmpDir, "a"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(tmpDir, "b"), 0755))
+	fileA := filepath.Join(tmpDir, "a", "a.go")
+	require.NoError(t, os.WriteFile(fileA, []byte(`package a
+
+import lib "test/lib"
+
+var _ = lib.Func
+`), 0644))
+	fileA2 := filepath.Join(tmpDir, "a", "a2.go")
+	require.NoError(t, os.WriteFile(fileA2, []byte(`package a
+
+import _ "test/lib"
+`), 0644))
+	fileB := filepath.Join(tmpDir, "b", "b.go")
+	require.NoError(t, os.WriteFile(fileB, []byte(`package b
+
+// "test/lib" はコメント内なのでインポートではない
+import "fmt"
+
+var _ = fmt.Sprint
+`), 0644))
+
+	modules := gomod.NewModuleMap(map[string]gomod.Module{
+		"test": {
+			Path: "test",
+			Root: tmpDir,
+		},
+	})
+
+	importers, err := ExtractImporters(context.Background(), "test/lib", []string{fileA, fileA2, fileB}, *modules)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"test/a"}, importers)
+
+	// 構文エラーのあるファイルは警告を出して除外する
+	broken := filepath.Join(tmpDir, "b", "broken.go")
+	require.NoError(t, os.WriteFile(broken, []byte("package b\n\nimport \"test/lib\"\n\nfunc f( {\n"), 0644))
+	var out bytes.Buffer
+	ctx := diag.WithReporter(context.Background(), diag.NewReporter(&out))
+	importers, err = ExtractImporters(ctx, "test/lib", []string{fileA, broken, fileB}, *modules)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"test/a"}, importers)
+	assert.Contains(t, out.String(), broken)
+
+	// キャンセルされたコンテキストではエラーを返す
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = ExtractImporters(canceled, "test/lib", []string{fileA, fileA2, fileB}, *modules)
+	assert.ErrorIs(t, err, context.Canceled)
+}
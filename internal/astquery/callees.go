@@ -0,0 +1,91 @@
+package astquery
+
+import (
+	"context"
+	"fmt"
+	"go/token"
+	"slices"
+	"strings"
+
+	"github.com/meian/rev-callgraph/internal/cache"
+	"github.com/meian/rev-callgraph/internal/gomod"
+	"github.com/meian/rev-callgraph/internal/progress"
+	"github.com/meian/rev-callgraph/internal/stats"
+	"github.com/meian/rev-callgraph/internal/summary"
+	"github.com/meian/rev-callgraph/internal/symbol"
+)
+
+// Callee は caller から呼び出されている関数/メソッドと、その呼び出し箇所を表します
+type Callee struct {
+	symbol.Function
+	// Pos は呼び出し式の位置
+	Pos token.Position
+}
+
+// ExtractCallees は mod 内の file に定義された caller の本体で呼び出されている関数/メソッドと
+// 呼び出し箇所のリストを、呼び出し箇所の順に返します。
+// 呼び出しは ExtractCallers と同じ規則で名前を解決します。
+//   - f() は同じパッケージに定義された関数 f (組み込み関数やローカル変数の呼び出しは除外)
+//   - X.f() で X がインポート名の場合はインポートしたパッケージの f
+//   - それ以外の x.f() は同じパッケージに定義されたメソッド f (型は区別しない)
+//
+// インポートしたパッケージの f はワークスペース外や型変換の場合も含むため、呼び出し側で定義を確認します。
+func ExtractCallees(ctx context.Context, caller symbol.Function, file string, mod gomod.Module, modules gomod.ModuleMap) ([]Callee, error) {
+	progress.Msgf(ctx, "extract callees for %s", caller)
+	defer stats.Start(ctx, stats.PhaseParse, caller.String())()
+	sf, err := cache.Load(ctx, file)
+	if err != nil {
+		return nil, fmt.Errorf("ASTパース失敗 %s: %w", file, err)
+	}
+	i := slices.IndexFunc(sf.Funcs, func(fn summary.Func) bool {
+		return fn.HasBody && fn.Name == caller.Name && fn.Recv == caller.TypeName
+	})
+	if i < 0 {
+		return nil, nil
+	}
+	defs, err := modules.PackageDefs(ctx, mod, caller.PkgPath)
+	if err != nil {
+		return nil, err
+	}
+	importMap := sf.ImportMap()
+
+	var callees []Callee
+	for _, call := range sf.Funcs[i].Calls {
+		pos := token.Position{Filename: file, Offset: call.Pos.Offset, Line: call.Pos.Line, Column: call.Pos.Column}
+		add := func(f symbol.Function) {
+			callees = append(callees, Callee{Function: f, Pos: pos})
+		}
+		switch impPath, isImport := importMap[call.X]; {
+		case !call.Selector:
+			f := symbol.Function{PkgPath: caller.PkgPath, Name: call.Name}
+			if _, ok := defs.Lookup(f); ok {
+				add(f)
+			}
+		case call.X != "" && isImport:
+			add(symbol.Function{PkgPath: impPath, Name: call.Name})
+		default:
+			// レシーバの型は解決できないため、同じ名前のメソッドを全て呼び出し先とする
+			for _, def := range sortedMethods(defs, call.Name) {
+				add(def.Function)
+			}
+		}
+	}
+	for _, c := range callees {
+		progress.Msgf(ctx, "  callee: %s", c)
+	}
+	return callees, nil
+}
+
+// sortedMethods は defs から name という名前のメソッドの定義を型名順に返します
+func sortedMethods(defs gomod.PackageDefs, name string) []gomod.Definition {
+	var methods []gomod.Definition
+	for _, def := range defs {
+		if def.Kind == gomod.DefMethod && def.Function.Name == name {
+			methods = append(methods, def)
+		}
+	}
+	slices.SortFunc(methods, func(a, b gomod.Definition) int {
+		return strings.Compare(a.Function.TypeName, b.Function.TypeName)
+	})
+	return methods
+}
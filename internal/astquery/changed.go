@@ -0,0 +1,76 @@
+package astquery
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/meian/rev-callgraph/internal/cache"
+	"github.com/meian/rev-callgraph/internal/diag"
+	"github.com/meian/rev-callgraph/internal/git"
+	"github.com/meian/rev-callgraph/internal/gomod"
+	"github.com/meian/rev-callgraph/internal/progress"
+	"github.com/meian/rev-callgraph/internal/symbol"
+)
+
+// ChangedFunctions は変更されたファイルと行範囲から、変更された行を含む関数/メソッド宣言を
+// 重複なく名前順に返します。
+// 宣言の範囲は関数名の行からボディの終わりの行までとし、ドキュメントコメントのみの変更は含みません。
+// .go 以外のファイル、vendor ディレクトリ内のファイル、存在しないファイルは無視し、
+// 解析できないファイルは診断を出力して除外します。
+func ChangedFunctions(ctx context.Context, changes map[string][]git.LineRange, modules gomod.ModuleMap) ([]symbol.Function, error) {
+	files := make([]string, 0, len(changes))
+	for file := range changes {
+		if filepath.Ext(file) != ".go" || slices.Contains(strings.Split(filepath.ToSlash(file), "/"), "vendor") {
+			continue
+		}
+		files = append(files, file)
+	}
+	slices.Sort(files)
+
+	seen := make(map[string]struct{})
+	var funcs []symbol.Function
+	for _, file := range files {
+		sf, err := cache.Load(ctx, file)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			if errors.Is(err, fs.ErrNotExist) {
+				progress.Msgf(ctx, "skip %s: %v", file, err)
+				continue
+			}
+			// 編集途中で構文エラーのあるファイルは診断を出力して続行する
+			diag.Warnf(ctx, file, "変更された関数の抽出をスキップしました: %v", err)
+			continue
+		}
+		pkgPath := determinePkgPath(file, modules)
+		for _, fn := range sf.Funcs {
+			if !fn.HasBody {
+				continue
+			}
+			start, end := fn.Pos.Line, fn.BodyEndLine
+			if !slices.ContainsFunc(changes[file], func(r git.LineRange) bool {
+				return r.Start <= end && start <= r.End
+			}) {
+				continue
+			}
+			f := symbol.Function{PkgPath: pkgPath, TypeName: fn.Recv, Name: fn.Name}
+			if _, ok := seen[f.String()]; ok {
+				continue
+			}
+			seen[f.String()] = struct{}{}
+			funcs = append(funcs, f)
+		}
+	}
+	slices.SortFunc(funcs, func(a, b symbol.Function) int {
+		return strings.Compare(a.String(), b.String())
+	})
+	for _, f := range funcs {
+		progress.Msgf(ctx, "  changed: %s", f)
+	}
+	return funcs, nil
+}
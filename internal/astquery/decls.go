@@ -0,0 +1,117 @@
+package astquery
+
+import (
+	"context"
+	"fmt"
+	"go/token"
+
+	"github.com/meian/rev-callgraph/internal/cache"
+	"github.com/meian/rev-callgraph/internal/diag"
+	"github.com/meian/rev-callgraph/internal/gomod"
+	"github.com/meian/rev-callgraph/internal/parallel"
+	"github.com/meian/rev-callgraph/internal/progress"
+	"github.com/meian/rev-callgraph/internal/stats"
+	"github.com/meian/rev-callgraph/internal/summary"
+	"github.com/meian/rev-callgraph/internal/symbol"
+)
+
+// Decl は関数/メソッド宣言と、その本体の呼び出しを表します
+type Decl struct {
+	symbol.Function
+	// Pos は関数名の位置
+	Pos token.Position
+	// Main は main パッケージの宣言かどうか
+	Main bool
+	// Directives はドキュメントコメント内のディレクティブ ("//" を除いたもの)
+	Directives []string
+	// Calls は本体の呼び出しを呼び出し箇所の順に並べたもの
+	Calls []Call
+	// Refs は本体で呼び出さずに参照した関数/メソッド (関数値・メソッド値) の候補を Calls と同じ規則で解決したもの
+	// 型を解決しないため、変数やフィールドの参照も同名の関数/メソッドの参照として含みます
+	Refs []Call
+}
+
+// Call は宣言の本体の呼び出しを ExtractCallers と同じ規則で解決したものです
+// Function と Method のいずれか一方が設定されます
+type Call struct {
+	// Function は f() (同じパッケージの関数) または X.f() (X はインポート名) で呼び出された関数
+	Function symbol.Function
+	// Method はそれ以外の x.f() で呼び出されたメソッドの名前 (レシーバの型は解決しない)
+	Method string
+	// Pos は呼び出し式 (参照の場合は識別子またはセレクタ式) の位置
+	Pos token.Position
+}
+
+// ExtractDecls は files に含まれる全ての関数/メソッド宣言と、その本体の呼び出しをファイル順に返します。
+// パッケージレベルの変数の初期化式 (関数リテラルを含む) の呼び出しと参照は、初期化時に評価されるため
+// そのパッケージの init 関数の宣言の呼び出しと参照として返します。
+// 呼び出しの名前は ExtractCallers と同じ規則で解決します。
+//   - f() は同じパッケージの関数 f
+//   - X.f() で X がインポート名の場合はインポートしたパッケージの f
+//   - それ以外の x.f() は名前が f のメソッド (型は区別しない)
+//
+// f() は組み込み関数やローカル変数の呼び出しの場合もあるため、呼び出し側で定義を確認します。
+// 解析できないファイルは診断を出力して除外します。
+func ExtractDecls(ctx context.Context, files []string, modules gomod.ModuleMap) ([]Decl, error) {
+	progress.Msgf(ctx, "extract declarations from %d files", len(files))
+	defer stats.Start(ctx, stats.PhaseParse, "declarations")()
+	results, errs, err := parallel.Map(ctx, files, func(file string) (*summary.File, error) {
+		sf, err := cache.Load(ctx, file)
+		if err != nil {
+			return nil, fmt.Errorf("ASTパース失敗 %s: %w", file, err)
+		}
+		return sf, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var decls []Decl
+	for i, file := range files {
+		if errs[i] != nil {
+			// 解析できないファイルは診断を出力して除外する
+			diag.Warnf(ctx, file, "宣言の抽出をスキップしました: %v", errs[i])
+			continue
+		}
+		sf := results[i]
+		importMap := sf.ImportMap()
+		pkgPath := determinePkgPath(file, modules)
+		resolve := func(calls []summary.Call) []Call {
+			var result []Call
+			for _, call := range calls {
+				c := Call{Pos: token.Position{Filename: file, Offset: call.Pos.Offset, Line: call.Pos.Line, Column: call.Pos.Column}}
+				switch impPath, isImport := importMap[call.X]; {
+				case !call.Selector:
+					c.Function = symbol.Function{PkgPath: pkgPath, Name: call.Name}
+				case call.X != "" && isImport:
+					c.Function = symbol.Function{PkgPath: impPath, Name: call.Name}
+				default:
+					c.Method = call.Name
+				}
+				result = append(result, c)
+			}
+			return result
+		}
+		for _, fn := range sf.Funcs {
+			decls = append(decls, Decl{
+				Function:   symbol.Function{PkgPath: pkgPath, TypeName: fn.Recv, Name: fn.Name},
+				Pos:        token.Position{Filename: file, Offset: fn.Pos.Offset, Line: fn.Pos.Line, Column: fn.Pos.Column},
+				Main:       sf.Package == "main",
+				Directives: fn.Directives,
+				Calls:      resolve(fn.Calls),
+				Refs:       resolve(fn.Refs),
+			})
+		}
+		// パッケージレベルの変数の初期化式の呼び出しと参照は init 関数からのものとする
+		if len(sf.VarCalls) > 0 || len(sf.VarRefs) > 0 {
+			decls = append(decls, Decl{
+				Function: symbol.Function{PkgPath: pkgPath, Name: "init"},
+				Pos:      token.Position{Filename: file, Offset: sf.VarPos.Offset, Line: sf.VarPos.Line, Column: sf.VarPos.Column},
+				Main:     sf.Package == "main",
+				Calls:    resolve(sf.VarCalls),
+				Refs:     resolve(sf.VarRefs),
+			})
+		}
+	}
+	return decls, nil
+}
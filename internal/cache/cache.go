@@ -0,0 +1,252 @@
+// Package cache はファイル単位の解析結果 (summary.File) を永続化するディスクキャッシュを提供します
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+
+	"github.com/meian/rev-callgraph/internal/srcfile"
+	"github.com/meian/rev-callgraph/internal/stats"
+	"github.com/meian/rev-callgraph/internal/summary"
+)
+
+// version はキャッシュエントリの形式バージョンです
+// summary.File の構造を変更した場合は更新して既存のエントリを無効化します
+const version = 4
+
+// entry はディスクに保存するキャッシュエントリです
+type entry struct {
+	Version int
+	Path    string
+	ModTime int64
+	Size    int64
+	File    *summary.File
+}
+
+// Store はファイルパスと更新日時・サイズをキーに要約をキャッシュします
+// ディスク上のエントリに加え、実行中に読み込んだ要約はメモリ上にも保持します
+type Store struct {
+	dir string
+
+	mu  sync.Mutex
+	mem map[string]*entry
+
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+// DefaultDir はユーザーキャッシュディレクトリ配下のキャッシュディレクトリを返します
+func DefaultDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "rev-callgraph"), nil
+}
+
+// Open は dir をキャッシュディレクトリとする Store を返します
+func Open(dir string) (*Store, error) {
+	if err := os.MkdirAll(filepath.Join(dir, "files"), 0o755); err != nil {
+		return nil, err
+	}
+	return &Store{dir: dir, mem: make(map[string]*entry)}, nil
+}
+
+// Dir はキャッシュディレクトリを返します
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+// Load は path の要約を返します
+// 更新日時とサイズが一致するエントリがあればそれを使い、なければ解析して保存します
+func (s *Store) Load(path string) (*summary.File, error) {
+	f, _, err := s.load(path)
+	return f, err
+}
+
+// load は path の要約と、キャッシュにヒットしたかどうかを返します
+func (s *Store) load(path string) (*summary.File, bool, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, false, err
+	}
+	valid := func(e *entry) bool {
+		return e != nil && e.Version == version && e.Path == path &&
+			e.ModTime == info.ModTime().UnixNano() && e.Size == info.Size()
+	}
+
+	s.mu.Lock()
+	e := s.mem[path]
+	s.mu.Unlock()
+	if valid(e) {
+		s.hits.Add(1)
+		return e.File, true, nil
+	}
+	if e = s.read(path); valid(e) {
+		s.hits.Add(1)
+		s.remember(e)
+		return e.File, true, nil
+	}
+
+	s.misses.Add(1)
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false, err
+	}
+	f, err := summary.Parse(path, src)
+	if err != nil {
+		return nil, false, err
+	}
+	e = &entry{Version: version, Path: path, ModTime: info.ModTime().UnixNano(), Size: info.Size(), File: f}
+	s.remember(e)
+	// 書き込みに失敗しても解析結果は利用できるため無視する
+	_ = s.write(e)
+	return f, false, nil
+}
+
+// remember はエントリをメモリ上に保持します
+func (s *Store) remember(e *entry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mem[e.Path] = e
+}
+
+// entryPath は path のエントリを保存するファイルパスを返します
+func (s *Store) entryPath(path string) string {
+	sum := sha256.Sum256([]byte(path))
+	name := hex.EncodeToString(sum[:])
+	return filepath.Join(s.dir, "files", name[:2], name+".gob")
+}
+
+// read はディスクからエントリを読み込みます
+// 存在しない、または壊れている場合は nil を返します
+func (s *Store) read(path string) *entry {
+	f, err := os.Open(s.entryPath(path))
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	var e entry
+	if err := gob.NewDecoder(f).Decode(&e); err != nil {
+		return nil
+	}
+	return &e
+}
+
+// write はエントリをディスクに保存します
+// 書き込み途中のファイルを読まないよう一時ファイルに書いてからリネームします
+func (s *Store) write(e *entry) error {
+	dst := s.entryPath(e.Path)
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(dst), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if err := gob.NewEncoder(tmp).Encode(e); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), dst)
+}
+
+// Counts は実行中のキャッシュヒット数とミス数を返します
+func (s *Store) Counts() (hits, misses int64) {
+	return s.hits.Load(), s.misses.Load()
+}
+
+// Stats はディスク上のキャッシュの統計情報を表します
+type Stats struct {
+	// Dir はキャッシュディレクトリ
+	Dir string
+	// Entries はエントリ数
+	Entries int
+	// Bytes はエントリの合計サイズ
+	Bytes int64
+}
+
+// Stats はディスク上のキャッシュの統計情報を返します
+func (s *Store) Stats() (Stats, error) {
+	st := Stats{Dir: s.dir}
+	err := filepath.WalkDir(filepath.Join(s.dir, "files"), func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || filepath.Ext(path) != ".gob" {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		st.Entries++
+		st.Bytes += info.Size()
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return st, nil
+	}
+	return st, err
+}
+
+// Clear はディスク上とメモリ上の全てのエントリを削除します
+func (s *Store) Clear() error {
+	s.mu.Lock()
+	s.mem = make(map[string]*entry)
+	s.mu.Unlock()
+	return os.RemoveAll(filepath.Join(s.dir, "files"))
+}
+
+type storeKey struct{}
+
+// WithStore はコンテキストに Store を追加します。
+func WithStore(ctx context.Context, s *Store) context.Context {
+	return context.WithValue(ctx, storeKey{}, s)
+}
+
+// FromContext はコンテキストに紐づけられた Store を返します。
+// Store が存在しない場合は nil を返します。
+func FromContext(ctx context.Context) *Store {
+	s, _ := ctx.Value(storeKey{}).(*Store)
+	return s
+}
+
+// Load はコンテキストに紐づけられた Store を使って path の要約を返します。
+// Store が存在しない場合はキャッシュを使わずに解析します。
+// ファイルサイズが srcfile の上限を超える場合は解析せずにエラーを返します。
+func Load(ctx context.Context, path string) (*summary.File, error) {
+	if err := srcfile.Check(ctx, path); err != nil {
+		return nil, err
+	}
+	if s := FromContext(ctx); s != nil {
+		f, hit, err := s.load(path)
+		if err == nil {
+			if hit {
+				stats.Add(ctx, stats.CacheHits, 1)
+			} else {
+				stats.Add(ctx, stats.FilesParsed, 1)
+			}
+		}
+		return f, err
+	}
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	stats.Add(ctx, stats.FilesParsed, 1)
+	return summary.Parse(path, src)
+}
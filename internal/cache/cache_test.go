@@ -0,0 +1,66 @@
+package cache_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/meian/rev-callgraph/internal/cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore_Load(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(t.TempDir(), "a.go")
+	require.NoError(t, os.WriteFile(src, []byte(`package a
+
+func A() { B() }
+
+func B() {}
+`), 0644))
+
+	s, err := cache.Open(dir)
+	require.NoError(t, err)
+	f, err := s.Load(src)
+	require.NoError(t, err)
+	assert.Equal(t, "a", f.Package)
+	require.Len(t, f.Funcs, 2)
+	require.Len(t, f.Funcs[0].Calls, 1)
+	assert.Equal(t, "B", f.Funcs[0].Calls[0].Name)
+	hits, misses := s.Counts()
+	assert.Equal(t, int64(0), hits)
+	assert.Equal(t, int64(1), misses)
+
+	st, err := s.Stats()
+	require.NoError(t, err)
+	assert.Equal(t, 1, st.Entries)
+
+	// 別の実行ではディスク上のエントリを利用する
+	s, err = cache.Open(dir)
+	require.NoError(t, err)
+	_, err = s.Load(src)
+	require.NoError(t, err)
+	hits, misses = s.Counts()
+	assert.Equal(t, int64(1), hits)
+	assert.Equal(t, int64(0), misses)
+
+	// ファイルが変更されたらそのファイルだけ解析し直す
+	require.NoError(t, os.WriteFile(src, []byte(`package a
+
+func A() {}
+`), 0644))
+	require.NoError(t, os.Chtimes(src, time.Now(), time.Now().Add(time.Second)))
+	f, err = s.Load(src)
+	require.NoError(t, err)
+	require.Len(t, f.Funcs, 1)
+	hits, misses = s.Counts()
+	assert.Equal(t, int64(1), hits)
+	assert.Equal(t, int64(1), misses)
+
+	require.NoError(t, s.Clear())
+	st, err = s.Stats()
+	require.NoError(t, err)
+	assert.Equal(t, 0, st.Entries)
+}
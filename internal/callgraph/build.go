@@ -0,0 +1,148 @@
+package callgraph
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"github.com/meian/rev-callgraph/internal/gomod"
+	"github.com/meian/rev-callgraph/internal/graph"
+	"github.com/meian/rev-callgraph/internal/parallel"
+	"github.com/meian/rev-callgraph/internal/progress"
+	"github.com/meian/rev-callgraph/internal/stats"
+)
+
+// vertex は探索対象の関数 (またはパッケージ) と、その定義を含むモジュールです
+type vertex struct {
+	mod  gomod.Module
+	name string
+	// sites は探索元のノードとの関係の根拠となる位置 (呼び出し元・呼び出し先の場合は呼び出し箇所)
+	sites []graph.Position
+	// external はワークスペース外の関数の場合に stdlib または external
+	external string
+}
+
+// id はノードのIDを返します
+// vendored モジュール内のコピーは同名の関数と区別します
+func (v vertex) id() string {
+	return graph.NodeID(v.name, v.mod.VendoredBy)
+}
+
+// traversal はグラフの構築方法を表します
+type traversal struct {
+	// maxDepth>0 の場合、root からの最短距離が maxDepth に達したノードは展開しない
+	maxDepth int
+	// newNode は頂点に対応するノードを作成する
+	newNode func(vertex) *graph.Node
+	// expand は頂点の呼び出し元 (またはインポート元) を返す
+	// forward の場合は呼び出し先を返す
+	expand func(context.Context, vertex) ([]vertex, error)
+	// forward は expand が呼び出し先を返すかどうか
+	forward bool
+	// leaf が true を返したノードは展開しない (nil の場合は全て展開する)
+	leaf func(*graph.Node) bool
+	// done は深さごとの展開の後に呼ばれ、true を返すと探索を終了する (nil の場合は終了しない)
+	done func(*graph.Graph) bool
+}
+
+// buildGraph は root から expand で得られる呼び出し元 (forward の場合は呼び出し先) を幅優先で辿り、グラフを構築します
+// 各ノードの呼び出し元は一度だけ探索し、複数の経路から到達したノードは同じノードとして扱います
+// root の探索に失敗した場合はエラーを返し、それ以外のノードの失敗は呼び出し元なしとして続行します
+// コンテキストがキャンセルされた場合は、それまでに構築したグラフと ctx.Err() を返します
+// その際に呼び出し元を展開できなかったノードには Truncated を設定します
+// 構築したグラフの循環に含まれるノードには Cycle を設定します
+func buildGraph(ctx context.Context, root vertex, t traversal) (*graph.Graph, error) {
+	return buildGraphFrom(ctx, []vertex{root}, t)
+}
+
+// buildGraphFrom は複数の起点 roots から buildGraph と同様にグラフを構築します
+// グラフの Root は roots の先頭とし、その他の起点も深さ0のノードとして同時に展開します
+// いずれかの起点の探索に失敗した場合はエラーを返します
+func buildGraphFrom(ctx context.Context, roots []vertex, t traversal) (*graph.Graph, error) {
+	defer stats.Start(ctx, stats.PhaseGraph, roots[0].name)()
+	g := graph.New(t.newNode(roots[0]))
+	g.Forward = t.forward
+	frontier := []vertex{roots[0]}
+	for _, root := range roots[1:] {
+		if _, added := g.AddNode(t.newNode(root)); added {
+			frontier = append(frontier, root)
+		}
+	}
+	for depth := 0; len(frontier) > 0; depth++ {
+		// 最大深さに到達したら探索終了（0は無制限）
+		if t.maxDepth > 0 && depth >= t.maxDepth {
+			progress.Msgf(ctx, "max depth reached at %d nodes", len(frontier))
+			break
+		}
+		if t.done != nil && t.done(g) {
+			break
+		}
+		if t.leaf != nil {
+			frontier = slices.DeleteFunc(frontier, func(v vertex) bool {
+				n, _ := g.Node(v.id())
+				return t.leaf(n)
+			})
+		}
+		// 同じ深さのノードは並列に探索し、結果は発見順に並べる
+		found, errs, err := parallel.Map(ctx, frontier, func(v vertex) ([]vertex, error) {
+			return t.expand(ctx, v)
+		})
+		canceled := err != nil
+		var next []vertex
+		for i, v := range frontier {
+			if errs[i] != nil && canceled {
+				// 中断により展開できなかったノード
+				markTruncated(ctx, g, v)
+				continue
+			}
+			if errs[i] != nil {
+				if depth == 0 {
+					return nil, errs[i]
+				}
+				progress.Msgf(ctx, "skip %s: %v", v.name, errs[i])
+				continue
+			}
+			for _, c := range found[i] {
+				if _, ok := g.Node(c.id()); !ok {
+					g.AddNode(t.newNode(c))
+					next = append(next, c)
+				}
+				caller, callee := c.id(), v.id()
+				if t.forward {
+					caller, callee = callee, caller
+				}
+				g.AddEdge(caller, callee)
+				for _, site := range c.sites {
+					g.AddSite(caller, callee, site)
+				}
+			}
+		}
+		frontier = next
+		if canceled {
+			for _, v := range frontier {
+				markTruncated(ctx, g, v)
+			}
+			progress.Msgf(ctx, "search interrupted: %v", err)
+			g.MarkCycles()
+			stats.Add(ctx, stats.Nodes, len(g.Nodes))
+			stats.Add(ctx, stats.Edges, len(g.Edges))
+			return g, err
+		}
+	}
+	g.MarkCycles()
+	stats.Add(ctx, stats.Nodes, len(g.Nodes))
+	stats.Add(ctx, stats.Edges, len(g.Edges))
+	return g, nil
+}
+
+// markTruncated は v のノードに、コンテキストが中断された理由を設定します
+func markTruncated(ctx context.Context, g *graph.Graph, v vertex) {
+	n, ok := g.Node(v.id())
+	if !ok {
+		return
+	}
+	n.Truncated = graph.TruncatedInterrupted
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		n.Truncated = graph.TruncatedTimeout
+	}
+}
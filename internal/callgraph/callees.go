@@ -0,0 +1,108 @@
+package callgraph
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/meian/rev-callgraph/internal/astquery"
+	"github.com/meian/rev-callgraph/internal/gomod"
+	"github.com/meian/rev-callgraph/internal/graph"
+	"github.com/meian/rev-callgraph/internal/progress"
+	"github.com/meian/rev-callgraph/internal/stats"
+	"github.com/meian/rev-callgraph/internal/symbol"
+)
+
+// 呼び出し先がワークスペース外の場合の種別
+const (
+	// externalStdlib は標準ライブラリ
+	externalStdlib = "stdlib"
+	// externalModule はワークスペース外のモジュール
+	externalModule = "external"
+)
+
+// CalleesGraph は target から呼び出されている関数/メソッドを再帰的に探索し、呼び出し先方向のグラフとして構築する
+// 呼び出し先はワークスペース内の関数/メソッドのみ展開し、external=true の場合は
+// 標準ライブラリやワークスペース外のモジュールの関数も展開しない末端のノードとして含める
+// maxDepth=0の場合は無制限
+// コンテキストが中断された場合は、それまでに構築したグラフと ctx.Err() を返す
+func CalleesGraph(ctx context.Context, mod gomod.Module, target string, mods gomod.ModuleMap, maxDepth int, external bool) (*graph.Graph, error) {
+	return buildGraph(ctx, vertex{mod: mod, name: target}, traversal{
+		maxDepth: maxDepth,
+		forward:  true,
+		newNode: func(v vertex) *graph.Node {
+			if v.external != "" {
+				return externalNode(v)
+			}
+			return functionNode(ctx, v, mods)
+		},
+		expand: func(ctx context.Context, v vertex) ([]vertex, error) {
+			return searchCallees(ctx, v, mods, external)
+		},
+		leaf: func(n *graph.Node) bool {
+			return n.External != ""
+		},
+	})
+}
+
+// externalNode はワークスペース外の関数を表すグラフのノードを返します
+func externalNode(v vertex) *graph.Node {
+	n := &graph.Node{ID: v.id(), Name: v.name, Kind: graph.KindFunc, External: v.external}
+	if f, err := symbol.ParseFunction(v.name); err == nil && f.IsMethod() {
+		n.Kind = graph.KindMethod
+	}
+	return n
+}
+
+// searchCallees は v の本体から呼び出されている関数/メソッドと、その定義を含むモジュールを返します
+// 定義が見つからない呼び出し先 (型変換や変数に格納された関数等) は除外します
+// external=true の場合はワークスペース外のパッケージの関数を種別付きで含めます
+func searchCallees(ctx context.Context, v vertex, mods gomod.ModuleMap, external bool) ([]vertex, error) {
+	progress.Msgf(ctx, "search callees for %s in %s", v.name, v.mod.Path)
+	defer stats.Start(ctx, stats.PhaseTarget, v.name)()
+
+	f, err := symbol.ParseFunction(v.name)
+	if err != nil {
+		return nil, err
+	}
+	def, ok, err := mods.Definition(ctx, v.mod, f)
+	if err != nil {
+		return nil, fmt.Errorf("定義の検索失敗: %w", err)
+	}
+	if !ok {
+		return nil, nil
+	}
+	calleeList, err := astquery.ExtractCallees(ctx, f, def.Pos.Filename, v.mod, mods)
+	if err != nil {
+		return nil, fmt.Errorf("AST解析失敗: %w", err)
+	}
+
+	var callees []vertex
+	for _, c := range calleeList {
+		sites := []graph.Position{{File: c.Pos.Filename, Line: c.Pos.Line, Column: c.Pos.Column}}
+		if _, inWorkspace := mods.FindByPackage(c.PkgPath); !inWorkspace && v.mod.VendoredBy == "" {
+			if external {
+				callees = append(callees, vertex{name: c.String(), sites: sites, external: externalKind(c.PkgPath)})
+			}
+			continue
+		}
+		modOfCallee, err := mods.FindByFunctionIn(ctx, c.Function, v.mod.VendoredBy)
+		if err != nil {
+			return nil, err
+		}
+		if modOfCallee != nil {
+			callees = append(callees, vertex{mod: *modOfCallee, name: c.String(), sites: sites})
+		}
+	}
+	return callees, nil
+}
+
+// externalKind はワークスペース外のパッケージが標準ライブラリかどうかを、
+// パスの先頭の要素にドットを含まないかで判定して種別を返します
+func externalKind(pkgPath string) string {
+	first, _, _ := strings.Cut(pkgPath, "/")
+	if strings.Contains(first, ".") {
+		return externalModule
+	}
+	return externalStdlib
+}
@@ -3,23 +3,25 @@ package callgraph
 import (
 	"context"
 	"fmt"
-	"go/parser"
-	"go/token"
-	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/meian/rev-callgraph/internal/astquery"
+	"github.com/meian/rev-callgraph/internal/cache"
 	"github.com/meian/rev-callgraph/internal/contextutil"
 	"github.com/meian/rev-callgraph/internal/gomod"
+	"github.com/meian/rev-callgraph/internal/graph"
 	"github.com/meian/rev-callgraph/internal/grep"
+	"github.com/meian/rev-callgraph/internal/parallel"
 	"github.com/meian/rev-callgraph/internal/progress"
+	"github.com/meian/rev-callgraph/internal/stats"
 	"github.com/meian/rev-callgraph/internal/symbol"
 )
 
 // getPkgNameFromPath はパッケージパスからGoのpackage宣言名を取得します
-func getPkgNameFromPath(pkgPath string, mods gomod.ModuleMap) string {
+func getPkgNameFromPath(ctx context.Context, pkgPath string, mods gomod.ModuleMap) string {
 	mod, ok := mods.FindByPackage(pkgPath)
 	if !ok {
 		return ""
@@ -36,50 +38,92 @@ func getPkgNameFromPath(pkgPath string, mods gomod.ModuleMap) string {
 		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".go") {
 			continue
 		}
-		fset := token.NewFileSet()
-		file, err := parser.ParseFile(fset, filepath.Join(dir, entry.Name()), nil, parser.PackageClauseOnly)
-		if err == nil && file.Name != nil {
-			return file.Name.Name
+		file, err := cache.Load(ctx, filepath.Join(dir, entry.Name()))
+		if err == nil {
+			return file.Package
 		}
 	}
 	return ""
 }
 
-// CallersTree はツリー構造で呼び出し元を再帰的に構築する
+// CallersGraph は target の呼び出し元を再帰的に探索し、グラフとして構築する
+// 各関数の呼び出し元は一度だけ探索するため、複数の経路から呼ばれる関数があっても探索は重複しない
 // maxDepth=0の場合は無制限
-func CallersTree(ctx context.Context, mod gomod.Module, target string, mods gomod.ModuleMap, depth int, seen map[string]struct{}, maxDepth int) (*symbol.CallNode, error) {
-	if contextutil.IsCanceledOrTimedOut(ctx) {
-		return nil, ctx.Err()
-	}
-	if seen == nil {
-		seen = make(map[string]struct{})
-	}
+// コンテキストが中断された場合は、それまでに構築したグラフと ctx.Err() を返す
+func CallersGraph(ctx context.Context, mod gomod.Module, target string, mods gomod.ModuleMap, maxDepth int) (*graph.Graph, error) {
+	return buildGraph(ctx, vertex{mod: mod, name: target}, callersTraversal(ctx, mods, maxDepth))
+}
 
-	// mainパッケージか判定
-	isMain := false
-	if idx := strings.LastIndexAny(target, ".#"); idx > 0 {
-		pkgPath := target[:idx]
-		isMain = getPkgNameFromPath(pkgPath, mods) == "main"
+// callersTraversal は呼び出し元を辿るグラフの構築方法を返します
+func callersTraversal(ctx context.Context, mods gomod.ModuleMap, maxDepth int) traversal {
+	return traversal{
+		maxDepth: maxDepth,
+		newNode: func(v vertex) *graph.Node {
+			return functionNode(ctx, v, mods)
+		},
+		expand: func(ctx context.Context, v vertex) ([]vertex, error) {
+			return searchCallers(ctx, v.mod, v.name, mods)
+		},
 	}
+}
 
-	// サイクル検出
-	if _, exists := seen[target]; exists {
-		progress.Msgf(ctx, "cycle detected for %s in %s", target, mod.Path)
-		return &symbol.CallNode{Name: target, Cycled: true, Main: isMain}, nil
+// functionNode は関数/メソッドを表すグラフのノードを返します
+// 定義位置が特定できない場合は Pos を設定しません
+func functionNode(ctx context.Context, v vertex, mods gomod.ModuleMap) *graph.Node {
+	n := &graph.Node{ID: v.id(), Name: v.name, Kind: graph.KindFunc, Module: v.mod.Path, Vendor: v.mod.VendoredBy}
+	f, err := symbol.ParseFunction(v.name)
+	if err != nil {
+		return n
+	}
+	if f.IsMethod() {
+		n.Kind = graph.KindMethod
 	}
-	// 最大深さに到達したら探索終了（0は無制限）
-	if maxDepth > 0 && depth >= maxDepth {
-		progress.Msgf(ctx, "max depth reached for %s in %s", target, mod.Path)
-		return &symbol.CallNode{Name: target, Main: isMain}, nil
+	// mainパッケージか判定
+	n.Main = getPkgNameFromPath(ctx, f.PkgPath, mods) == "main"
+	if def, ok, err := mods.Definition(ctx, v.mod, f); err == nil && ok {
+		n.Pos = &graph.Position{File: def.Pos.Filename, Line: def.Pos.Line, Column: def.Pos.Column}
 	}
+	return n
+}
 
+// searchCallers は mod と mod を参照しているモジュールから target の呼び出し元を探索する
+// mod 自身の探索に失敗した場合はエラーを返し、参照元モジュールの失敗はスキップする
+func searchCallers(ctx context.Context, mod gomod.Module, target string, mods gomod.ModuleMap) ([]vertex, error) {
 	progress.Msgf(ctx, "search callers for %s in %s", target, mod.Path)
-	seen[target] = struct{}{}
-
-	var callers []*symbol.CallNode
+	defer stats.Start(ctx, stats.PhaseTarget, target)()
 
 	// 同一モジュール内を探索
-	files, err := grep.SearchFiles(ctx, mod.Root, target)
+	callers, err := findCallers(ctx, mod, target, mods)
+	if err != nil {
+		return nil, err
+	}
+
+	// 参照元モジュールを並列に探索
+	refMods := mods.ReferencedBy(mod)
+	refCallers, errs, err := parallel.Map(ctx, refMods, func(refMod gomod.Module) ([]vertex, error) {
+		progress.Msgf(ctx, "search for referenced module: %s", refMod.Path)
+		return findCallers(ctx, refMod, target, mods)
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i := range refMods {
+		if errs[i] != nil {
+			continue // 参照元1つ失敗しても他は続行
+		}
+		callers = append(callers, refCallers[i]...)
+	}
+	// 呼び出し元は名前の順に並べる (vendored モジュール内の同名のコピーは探索順)
+	slices.SortStableFunc(callers, func(a, b vertex) int {
+		return strings.Compare(a.name, b.name)
+	})
+	return callers, nil
+}
+
+// findCallers は mod 内で target を呼び出している関数/メソッドと、その定義を含むモジュールを返します
+// 定義を含むモジュールが見つからない呼び出し元は除外します
+func findCallers(ctx context.Context, mod gomod.Module, target string, mods gomod.ModuleMap) ([]vertex, error) {
+	files, err := grep.SearchFiles(ctx, mod.Root, astquery.CallIdent(target))
 	if err != nil {
 		return nil, fmt.Errorf("grep.SearchFiles失敗: %w", err)
 	}
@@ -87,53 +131,19 @@ func CallersTree(ctx context.Context, mod gomod.Module, target string, mods gomo
 	if err != nil {
 		return nil, fmt.Errorf("AST解析失敗: %w", err)
 	}
+	var callers []vertex
 	for _, c := range callerList {
 		if contextutil.IsCanceledOrTimedOut(ctx) {
 			return nil, ctx.Err()
 		}
-		modOfCaller, err := mods.FindByFunction(ctx, c)
+		modOfCaller, err := mods.FindByFunctionIn(ctx, c.Function, mod.VendoredBy)
 		if err == nil && modOfCaller != nil {
-			child, err := CallersTree(ctx, *modOfCaller, c.String(), mods, depth+1, cloneSeen(seen), maxDepth)
-			if err != nil {
-				continue
-			}
-			callers = append(callers, child)
-		}
-	}
-
-	// 参照元モジュールを探索
-	for _, refMod := range mods.ReferencedBy(mod) {
-		if contextutil.IsCanceledOrTimedOut(ctx) {
-			return nil, ctx.Err()
-		}
-		progress.Msgf(ctx, "search for referenced module: %s", refMod.Path)
-		files, err := grep.SearchFiles(ctx, refMod.Root, target)
-		if err != nil {
-			continue // 参照元1つ失敗しても他は続行
-		}
-		callerList, err := astquery.ExtractCallers(ctx, target, files, mods)
-		if err != nil {
-			continue
-		}
-		for _, c := range callerList {
-			if contextutil.IsCanceledOrTimedOut(ctx) {
-				return nil, ctx.Err()
-			}
-			modOfCaller, err := mods.FindByFunction(ctx, c)
-			if err == nil && modOfCaller != nil {
-				child, err := CallersTree(ctx, *modOfCaller, c.String(), mods, depth+1, cloneSeen(seen), maxDepth)
-				if err != nil {
-					continue
-				}
-				callers = append(callers, child)
+			sites := make([]graph.Position, 0, c.Count())
+			for _, s := range c.Sites {
+				sites = append(sites, graph.Position{File: s.Filename, Line: s.Line, Column: s.Column})
 			}
+			callers = append(callers, vertex{mod: *modOfCaller, name: c.String(), sites: sites})
 		}
 	}
-
-	return &symbol.CallNode{Name: target, Callers: callers, Main: isMain}, nil
-}
-
-// cloneSeen はサイクル検出用マップをコピーする
-func cloneSeen(seen map[string]struct{}) map[string]struct{} {
-	return maps.Clone(seen)
+	return callers, nil
 }
@@ -4,16 +4,20 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/meian/rev-callgraph/internal/callgraph"
 	"github.com/meian/rev-callgraph/internal/gomod"
+	"github.com/meian/rev-callgraph/internal/graph"
+	"github.com/meian/rev-callgraph/internal/symbol"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
-func TestCallersTree_ContextCancellation(t *testing.T) {
+func TestCallersGraph_ContextCancellation(t *testing.T) {
 	ctx := context.Background()
 	testMod, modules, err := scanTestModules(ctx)
 	require.NoError(t, err)
@@ -22,49 +26,116 @@ func TestCallersTree_ContextCancellation(t *testing.T) {
 	cancelCtx, cancel := context.WithCancel(context.Background())
 	cancel()
 
-	// CallersTree を実行（キャンセルされたコンテキストで）
+	// CallersGraph を実行（キャンセルされたコンテキストで）
 	target := "github.com/meian/rev-callgraph/testdata/app.main"
-	_, err = callgraph.CallersTree(cancelCtx, *testMod, target, *modules, 0, nil, 0)
+	result, err := callgraph.CallersGraph(cancelCtx, *testMod, target, *modules, 0)
 
 	// キャンセルエラーが返されることを確認
 	assert.True(t, errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled),
 		"context.DeadlineExceeded または context.Canceled が期待されますが、実際: %v", err)
+	// 展開できなかった起点ノードを中断扱いとしたグラフが返されることを確認
+	require.NotNil(t, result)
+	assert.Equal(t, graph.TruncatedInterrupted, result.RootNode().Truncated)
+	assert.Equal(t, []*graph.Node{result.RootNode()}, result.Truncated())
+	assert.Empty(t, result.Edges)
 }
 
-func TestCallersTree_CycleDetection(t *testing.T) {
+func TestCallersGraph_Timeout(t *testing.T) {
 	ctx := context.Background()
 	testMod, modules, err := scanTestModules(ctx)
 	require.NoError(t, err)
 
-	// サイクル検出のテスト（既にseenマップに含まれている場合）
-	seen := map[string]struct{}{
-		"github.com/meian/rev-callgraph/testdata/app.main": {},
-	}
-
-	result, err := callgraph.CallersTree(ctx, *testMod, "github.com/meian/rev-callgraph/testdata/app.main", *modules, 0, seen, 0)
-	assert.NoError(t, err, "予期しないエラー")
-	require.NotNil(t, result, "結果が nil です")
-	assert.True(t, result.Cycled, "サイクル検出が期待されましたが、Cycled フラグが false です")
+	timeoutCtx, cancel := context.WithTimeout(ctx, 0)
+	defer cancel()
+	target := "github.com/meian/rev-callgraph/testdata/app.main"
+	result, err := callgraph.CallersGraph(timeoutCtx, *testMod, target, *modules, 0)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	require.NotNil(t, result)
+	assert.Equal(t, graph.TruncatedTimeout, result.RootNode().Truncated)
+	assert.Equal(t, graph.TruncatedTimeout, result.Tree(0).Truncated)
 }
 
-func TestCallersTree_MaxDepth(t *testing.T) {
+func TestCallersGraph_CycleDetection(t *testing.T) {
 	ctx := context.Background()
 	testMod, modules, err := scanTestModules(ctx)
 	require.NoError(t, err)
 
-	// 最大深度のテスト（depth >= maxDepth の場合）
-	result, err := callgraph.CallersTree(ctx, *testMod, "github.com/meian/rev-callgraph/testdata/app.main", *modules, 1, nil, 1)
+	// 再帰呼び出しは自身を呼び出し元として共有し、展開時に経路上のサイクルとして検出する
+	target := "github.com/meian/rev-callgraph/testdata/app.Recurse"
+	result, err := callgraph.CallersGraph(ctx, *testMod, target, *modules, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []graph.Edge{{Caller: target, Callee: target}}, result.Edges, "再帰呼び出しは自身へのエッジとして保持されることが期待されます")
+	file := result.RootNode().Pos.File
+	assert.Equal(t, &graph.TreeNode{
+		Name:  target,
+		Main:  true,
+		Cycle: 1,
+		Callers: []*graph.TreeNode{
+			{Name: target, Main: true, Cycle: 1, Cycled: true, Count: 1, Sites: []string{file + ":15:2"}},
+		},
+	}, result.Tree(0))
+}
+
+func TestCallersGraph_MaxDepth(t *testing.T) {
+	ctx := context.Background()
+	_, modules, err := scanTestModules(ctx)
+	require.NoError(t, err)
+
+	target := "github.com/meian/rev-callgraph/testdata/foo.Target"
+	f, err := symbol.ParseFunction(target)
+	require.NoError(t, err)
+	mod, err := modules.FindByFunction(ctx, f)
+	require.NoError(t, err)
+
+	// 最大深度に到達したノードは呼び出し元を探索しない
+	result, err := callgraph.CallersGraph(ctx, *mod, target, *modules, 1)
 	assert.NoError(t, err, "予期しないエラー")
 	require.NotNil(t, result, "結果が nil です")
-	// 最大深度に到達した場合、callersは空になることを確認
-	assert.Empty(t, result.Callers, "最大深度に到達した場合、callersは空であることが期待されます")
+	require.NotEmpty(t, result.Edges)
+	for _, e := range result.Edges {
+		assert.Equal(t, target, e.Callee, "最大深度に到達した場合、呼び出し元は探索されないことが期待されます")
+	}
+}
+
+func TestCallersGraph_Diamond(t *testing.T) {
+	// 各層の関数が次の層の2関数を呼び出す菱形の呼び出しを多段に重ねる
+	// 経路数は 2^layers だが、各関数は一度だけ探索されノード・エッジも重複しない
+	const layers = 16
+	var b strings.Builder
+	b.WriteString("package d\n\nfunc Target() {}\n")
+	prev := []string{"Target"}
+	for i := range layers {
+		cur := []string{fmt.Sprintf("L%dA", i), fmt.Sprintf("L%dB", i)}
+		for _, name := range cur {
+			fmt.Fprintf(&b, "\nfunc %s() {\n", name)
+			for _, p := range prev {
+				fmt.Fprintf(&b, "\t%s()\n", p)
+			}
+			b.WriteString("}\n")
+		}
+		prev = cur
+	}
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "go.mod"), []byte("module example.com/d\n\ngo 1.24\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "d.go"), []byte(b.String()), 0644))
+
+	ctx := context.Background()
+	modules, err := gomod.Scan(ctx, root, gomod.ScanOptions{})
+	require.NoError(t, err)
+	mod, ok := modules.FindByPackage("example.com/d")
+	require.True(t, ok)
+
+	result, err := callgraph.CallersGraph(ctx, *mod, "example.com/d.Target", *modules, 0)
+	require.NoError(t, err)
+	assert.Len(t, result.Nodes, 1+2*layers)
+	assert.Len(t, result.Edges, 2+4*(layers-1), "同じ呼び出し元は一度だけ保持されることが期待されます")
 }
 
 // scanTestModules は testdata 配下の モジュールをスキャンし、テスト用の app モジュールとModuleMapを返す
 func scanTestModules(ctx context.Context) (*gomod.Module, *gomod.ModuleMap, error) {
 	testdataPath := filepath.Join("..", "..", "testdata")
 	modPath := "github.com/meian/rev-callgraph/testdata/app"
-	modules, err := gomod.Scan(ctx, testdataPath)
+	modules, err := gomod.Scan(ctx, testdataPath, gomod.ScanOptions{})
 	if err != nil {
 		return nil, nil, err
 	}
@@ -75,3 +146,593 @@ func scanTestModules(ctx context.Context) (*gomod.Module, *gomod.ModuleMap, erro
 	}
 	return nil, modules, fmt.Errorf("%s モジュールが見つかりません", modPath)
 }
+
+func TestImportersGraph(t *testing.T) {
+	ctx := context.Background()
+	_, modules, err := scanTestModules(ctx)
+	require.NoError(t, err)
+
+	pkg := "github.com/meian/rev-callgraph/testdata/bar"
+	mod, ok := modules.FindByPackage(pkg)
+	require.True(t, ok)
+
+	result, err := callgraph.ImportersGraph(ctx, *mod, pkg, *modules, 0)
+	require.NoError(t, err)
+	assert.Equal(t, &graph.TreeNode{
+		Name: pkg,
+		Callers: []*graph.TreeNode{
+			{Name: "github.com/meian/rev-callgraph/testdata/qux", Callers: []*graph.TreeNode{
+				{Name: "github.com/meian/rev-callgraph/testdata/app/submain", Callers: []*graph.TreeNode{
+					{Name: "github.com/meian/rev-callgraph/testdata/app", Main: true},
+				}},
+			}},
+		},
+	}, result.Tree(0))
+}
+
+func TestCallersGraph_Vendor(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"foo/go.mod": "module example.com/foo\n\ngo 1.24\n",
+		"foo/foo.go": "package foo\n\nfunc Target() {}\n",
+		"svc/go.mod": "module example.com/svc\n\ngo 1.24\n\nrequire (\n\texample.com/foo v1.0.0\n\texample.com/lib v1.0.0\n)\n",
+		"svc/main.go": `package main
+
+import "example.com/lib"
+
+func main() { lib.Run() }
+`,
+		"svc/vendor/modules.txt":            "# example.com/foo v1.0.0\n## explicit\nexample.com/foo\n# example.com/lib v1.0.0\n## explicit\nexample.com/lib\n",
+		"svc/vendor/example.com/foo/foo.go": "package foo\n\nfunc Target() {}\n",
+		"svc/vendor/example.com/lib/lib.go": `package lib
+
+import "example.com/foo"
+
+func Run() { foo.Target() }
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+
+	ctx := context.Background()
+	target := "example.com/foo.Target"
+	f, err := symbol.ParseFunction(target)
+	require.NoError(t, err)
+
+	// vendor モードでなければ vendor 内の呼び出しは検出しない
+	modules, err := gomod.Scan(ctx, root, gomod.ScanOptions{})
+	require.NoError(t, err)
+	mod, err := modules.FindByFunction(ctx, f)
+	require.NoError(t, err)
+	result, err := callgraph.CallersGraph(ctx, *mod, target, *modules, 0)
+	require.NoError(t, err)
+	assert.Empty(t, result.Edges)
+
+	// vendor モードでは vendored コピー内の呼び出しを取り込み元付きで検出する
+	modules, err = gomod.Scan(ctx, root, gomod.ScanOptions{Vendor: true})
+	require.NoError(t, err)
+	mod, err = modules.FindByFunction(ctx, f)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(root, "foo"), mod.Root)
+	result, err = callgraph.CallersGraph(ctx, *mod, target, *modules, 0)
+	require.NoError(t, err)
+	assert.Equal(t, &graph.TreeNode{
+		Name: target,
+		Callers: []*graph.TreeNode{
+			{Name: "example.com/lib.Run", Vendor: "example.com/svc", Count: 1, Sites: []string{
+				filepath.Join(root, "svc", "vendor", "example.com", "lib", "lib.go") + ":5:14",
+			}, Callers: []*graph.TreeNode{
+				{Name: "example.com/svc.main", Main: true, Count: 1, Sites: []string{filepath.Join(root, "svc", "main.go") + ":5:15"}},
+			}},
+		},
+	}, result.Tree(0))
+
+	// vendored コピーのみに定義された関数も経路の始点とする
+	_, paths, err := callgraph.CallPaths(ctx, *mod, "example.com/lib.Run", target, *modules, 0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []graph.Path{{"example.com/lib.Run (vendored by example.com/svc)", target}}, paths)
+}
+
+func TestShortestCallers(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/s\n\ngo 1.24\n",
+		"s.go": `package s
+
+import "net/http"
+
+func Target() {}
+
+func A() { Target() }
+
+func B() { A(); A() }
+
+type H struct{}
+
+func (H) ServeHTTP(w http.ResponseWriter, r *http.Request) { B() }
+`,
+		"s_test.go":   "package s\n\nimport \"testing\"\n\nfunc TestTarget(t *testing.T) { Target() }\n",
+		"cmd/main.go": "package main\n\nimport \"example.com/s\"\n\nfunc main() { s.A() }\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+
+	ctx := context.Background()
+	modules, err := gomod.Scan(ctx, root, gomod.ScanOptions{})
+	require.NoError(t, err)
+	mod, ok := modules.FindByPackage("example.com/s")
+	require.True(t, ok)
+
+	// 起点に近い順にテスト、main、HTTP ハンドラの経路が得られる
+	g, paths, err := callgraph.ShortestCallers(ctx, *mod, "example.com/s.Target", *modules, 3, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []graph.Path{
+		{"example.com/s.TestTarget", "example.com/s.Target"},
+		{"example.com/s/cmd.main", "example.com/s.A", "example.com/s.Target"},
+		{"example.com/s.H#ServeHTTP", "example.com/s.B", "example.com/s.A", "example.com/s.Target"},
+	}, paths)
+	assert.Equal(t, []graph.Position{
+		{File: filepath.Join(root, "s.go"), Line: 9, Column: 12},
+		{File: filepath.Join(root, "s.go"), Line: 9, Column: 17},
+	}, g.Sites("example.com/s.B", "example.com/s.A"), "同じ呼び出し元の呼び出し箇所は全て保持されることが期待されます")
+
+	// k 件の起点が見つかった深さで探索を終了する
+	g, paths, err = callgraph.ShortestCallers(ctx, *mod, "example.com/s.Target", *modules, 1, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []graph.Path{{"example.com/s.TestTarget", "example.com/s.Target"}}, paths)
+	_, ok = g.Node("example.com/s.B")
+	assert.False(t, ok, "起点が見つかった深さより先は探索しないことが期待されます")
+}
+
+func TestCallPaths(t *testing.T) {
+	ctx := context.Background()
+	_, modules, err := scanTestModules(ctx)
+	require.NoError(t, err)
+
+	from := "github.com/meian/rev-callgraph/testdata/app.main"
+	to := "github.com/meian/rev-callgraph/testdata/foo.Target"
+	mod, ok := modules.FindByPackage("github.com/meian/rev-callgraph/testdata/foo")
+	require.True(t, ok)
+
+	// from に到達しない分岐 (foo.CallTarget 等) は経路に含まれない
+	_, paths, err := callgraph.CallPaths(ctx, *mod, from, to, *modules, 0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []graph.Path{
+		{from, "github.com/meian/rev-callgraph/testdata/app/submain.Caller", "github.com/meian/rev-callgraph/testdata/qux.Caller", "github.com/meian/rev-callgraph/testdata/bar.Caller", to},
+		{from, "github.com/meian/rev-callgraph/testdata/app.caller", "github.com/meian/rev-callgraph/testdata/app/submain.Caller", "github.com/meian/rev-callgraph/testdata/qux.Caller", "github.com/meian/rev-callgraph/testdata/bar.Caller", to},
+	}, paths)
+
+	_, paths, err = callgraph.CallPaths(ctx, *mod, from, to, *modules, 1, 0)
+	require.NoError(t, err)
+	assert.Len(t, paths, 1)
+
+	// 到達しない場合は経路なし
+	_, paths, err = callgraph.CallPaths(ctx, *mod, "github.com/meian/rev-callgraph/testdata/foo.CallTarget2", "github.com/meian/rev-callgraph/testdata/foo.CallTarget", *modules, 0, 0)
+	require.NoError(t, err)
+	assert.Empty(t, paths)
+
+	// from と to が同じ関数の場合はエラー
+	_, _, err = callgraph.CallPaths(ctx, *mod, to, to, *modules, 0, 0)
+	assert.Error(t, err)
+}
+
+func TestCallPaths_Limit(t *testing.T) {
+	root := t.TempDir()
+	src := `package p
+
+func Target() {}
+
+func A() { Target() }
+
+func B() { Target() }
+
+func From() { A(); B(); E() }
+
+func E() { C() }
+
+func C() { D() }
+
+func D() { Target() }
+`
+	require.NoError(t, os.WriteFile(filepath.Join(root, "go.mod"), []byte("module example.com/p\n\ngo 1.24\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "p.go"), []byte(src), 0644))
+	ctx := context.Background()
+	modules, err := gomod.Scan(ctx, root, gomod.ScanOptions{})
+	require.NoError(t, err)
+	mod, ok := modules.FindByPackage("example.com/p")
+	require.True(t, ok)
+
+	// limit 件の経路が見つかった深さで探索を終了し、それより深い呼び出し元は展開しない
+	g, paths, err := callgraph.CallPaths(ctx, *mod, "example.com/p.From", "example.com/p.Target", *modules, 2, 0)
+	require.NoError(t, err)
+	assert.Len(t, paths, 2)
+	_, ok = g.Node("example.com/p.E")
+	assert.False(t, ok, "limit 件の経路が見つかった深さより先は探索しないことが期待されます")
+
+	_, paths, err = callgraph.CallPaths(ctx, *mod, "example.com/p.From", "example.com/p.Target", *modules, 0, 0)
+	require.NoError(t, err)
+	assert.Len(t, paths, 3)
+}
+
+func TestEntryPoints(t *testing.T) {
+	ctx := context.Background()
+	_, modules, err := scanTestModules(ctx)
+	require.NoError(t, err)
+	mod, ok := modules.FindByPackage("github.com/meian/rev-callgraph/testdata/foo")
+	require.True(t, ok)
+
+	target := "github.com/meian/rev-callgraph/testdata/foo.Target"
+	_, eps, err := callgraph.EntryPoints(ctx, *mod, target, *modules, 0)
+	require.NoError(t, err)
+	type entry struct {
+		kind graph.EntryKind
+		id   string
+	}
+	var got []entry
+	for _, ep := range eps {
+		got = append(got, entry{ep.Kind, ep.Node.ID})
+		assert.Equal(t, ep.Node.ID, ep.Path[0], "経路はエントリポイントから始まることが期待されます")
+		assert.Equal(t, target, ep.Path[len(ep.Path)-1], "経路は target で終わることが期待されます")
+	}
+	// 呼び出し元のない末端のノードのみを種別、名前順に分類する
+	assert.Equal(t, []entry{
+		{graph.EntryMain, "github.com/meian/rev-callgraph/testdata/app.main"},
+		{graph.EntryExported, "github.com/meian/rev-callgraph/testdata/bar.ExpressionCaller"},
+		{graph.EntryExported, "github.com/meian/rev-callgraph/testdata/foo.CallMethod"},
+		{graph.EntryExported, "github.com/meian/rev-callgraph/testdata/foo.CallTarget"},
+		{graph.EntryExported, "github.com/meian/rev-callgraph/testdata/foo.CallTarget2"},
+		{graph.EntryExported, "github.com/meian/rev-callgraph/testdata/foo/foo2.CallFooTarget"},
+		{graph.EntryNoCallers, "github.com/meian/rev-callgraph/testdata/app.Recurse"},
+	}, got)
+	appDir, err := filepath.Abs(filepath.Join("..", "..", "testdata", "app"))
+	require.NoError(t, err)
+	assert.Equal(t, appDir, eps[0].Dir)
+}
+
+func TestCalleesGraph(t *testing.T) {
+	ctx := context.Background()
+	testMod, modules, err := scanTestModules(ctx)
+	require.NoError(t, err)
+
+	// 組み込み関数とワークスペース外の呼び出し先は含めず、再帰呼び出しは経路上のサイクルとして打ち切る
+	target := "github.com/meian/rev-callgraph/testdata/app.Recurse"
+	result, err := callgraph.CalleesGraph(ctx, *testMod, target, *modules, 2, false)
+	require.NoError(t, err)
+	assert.True(t, result.Forward)
+	file := result.RootNode().Pos.File
+	assert.Equal(t, &graph.TreeNode{
+		Name:  target,
+		Main:  true,
+		Cycle: 1,
+		Callees: []*graph.TreeNode{
+			{Name: "github.com/meian/rev-callgraph/testdata/app/submain.Caller", Count: 1, Sites: []string{file + ":14:2"}, Callees: []*graph.TreeNode{
+				{Name: "github.com/meian/rev-callgraph/testdata/qux.Caller", Count: 1, Sites: []string{filepath.Join(filepath.Dir(file), "submain", "submain.go") + ":11:2"}},
+			}},
+			{Name: target, Main: true, Cycle: 1, Cycled: true, Count: 1, Sites: []string{file + ":15:2"}},
+		},
+	}, result.Tree(0))
+	assert.Equal(t, []graph.Position{{File: file, Line: 14, Column: 2}},
+		result.Sites(target, "github.com/meian/rev-callgraph/testdata/app/submain.Caller"))
+
+	// external=true の場合は標準ライブラリの呼び出し先を展開しない末端として含める
+	result, err = callgraph.CalleesGraph(ctx, *testMod, "github.com/meian/rev-callgraph/testdata/app/submain.Caller", *modules, 1, true)
+	require.NoError(t, err)
+	n, ok := result.Node("fmt.Println")
+	require.True(t, ok)
+	assert.Equal(t, "stdlib", n.External)
+	assert.Empty(t, result.Callees("fmt.Println"))
+}
+
+func TestImpact(t *testing.T) {
+	ctx := context.Background()
+	_, modules, err := scanTestModules(ctx)
+	require.NoError(t, err)
+
+	const (
+		app     = "github.com/meian/rev-callgraph/testdata/app"
+		submain = "github.com/meian/rev-callgraph/testdata/app/submain"
+	)
+	changed := []symbol.Function{
+		{PkgPath: app, Name: "caller"},
+		{PkgPath: submain, Name: "Caller"},
+		// 定義が見つからない (削除された) 関数は除外する
+		{PkgPath: app, Name: "Removed"},
+	}
+	_, impact, err := callgraph.Impact(ctx, changed, *modules, 0)
+	require.NoError(t, err)
+	require.Len(t, impact.Changed, 2)
+
+	// エントリポイントは到達する変更された関数ごとに、その関数に至る経路を持つ
+	type entry struct {
+		kind    graph.EntryKind
+		changed string
+		path    graph.Path
+	}
+	var got []entry
+	for _, ep := range impact.EntryPoints {
+		got = append(got, entry{ep.Kind, ep.Changed, ep.Path})
+	}
+	assert.Equal(t, []entry{
+		{graph.EntryMain, app + ".caller", graph.Path{app + ".main", app + ".caller"}},
+		{graph.EntryMain, submain + ".Caller", graph.Path{app + ".main", submain + ".Caller"}},
+		{graph.EntryNoCallers, submain + ".Caller", graph.Path{app + ".Recurse", submain + ".Caller"}},
+	}, got)
+	assert.Equal(t, []string{app, submain}, impact.Packages)
+	appDir, err := filepath.Abs(filepath.Join("..", "..", "testdata", "app"))
+	require.NoError(t, err)
+	assert.Equal(t, []graph.Binary{{Package: app, Dir: appDir}}, impact.Binaries)
+}
+
+func TestAffectedTests(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"go.mod":          "module example.com/a\n\ngo 1.24\n",
+		"a.go":            "package a\n\nfunc Target() {}\n\nfunc Helper() { Target() }\n",
+		"a_test.go":       "package a\n\nimport \"testing\"\n\nfunc TestA(t *testing.T) { Helper() }\n\nfunc BenchmarkA(b *testing.B) { Target() }\n\nfunc helper(t *testing.T) { Target() }\n",
+		"a_ext_test.go":   "package a_test\n\nimport (\n\t\"testing\"\n\n\t\"example.com/a\"\n)\n\nfunc TestExt(t *testing.T) { a.Target() }\n",
+		"b/go.mod":        "module example.com/b\n\ngo 1.24\n\nrequire example.com/a v0.0.0\n",
+		"b/p/p.go":        "package p\n\nfunc Unrelated() {}\n",
+		"b/p/p_test.go":   "package p\n\nimport (\n\t\"testing\"\n\n\t\"example.com/a\"\n)\n\nfunc TestB(t *testing.T) { a.Helper() }\n",
+		"b/p/q/q_test.go": "package q\n\nimport \"testing\"\n\nfunc TestUnrelated(t *testing.T) {}\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+
+	ctx := context.Background()
+	modules, err := gomod.Scan(ctx, root, gomod.ScanOptions{})
+	require.NoError(t, err)
+	mod, ok := modules.FindByPackage("example.com/a")
+	require.True(t, ok)
+	g, err := callgraph.CallersGraph(ctx, *mod, "example.com/a.Target", *modules, 0)
+	require.NoError(t, err)
+
+	// 到達したテスト関数のみをパッケージディレクトリごとにまとめる (外部テストパッケージは同じディレクトリに含める)
+	pkgs := callgraph.AffectedTests(g, *modules, root)
+	assert.Equal(t, []graph.TestPackage{
+		{Package: "example.com/a", Dir: root, ModuleDir: ".", Pattern: ".", Tests: []string{"TestA", "TestExt"}, Benchmarks: []string{"BenchmarkA"}},
+		{Package: "example.com/b/p", Dir: filepath.Join(root, "b", "p"), ModuleDir: "b", Pattern: "./p", Tests: []string{"TestB"}},
+	}, pkgs)
+	assert.Equal(t, "go test . -run '^(TestA|TestExt)$' -bench '^(BenchmarkA)$'", pkgs[0].Command())
+	assert.Equal(t, "go -C b test ./p -run '^(TestB)$'", pkgs[1].Command())
+}
+
+func TestDeadCode(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/d\n\ngo 1.24\n",
+		"main.go": `package main
+
+import "example.com/d/lib"
+
+var run = func() { viaVar() }
+
+func main() { run(); lib.Exported() }
+
+func viaVar() {}
+
+func unused() {}
+
+//deadcode:ignore
+func kept() { keptCallee() }
+
+func keptCallee() {}
+`,
+		"lib/lib.go": `package lib
+
+type t struct{}
+
+func (t) m() {}
+
+func (t) n() {}
+
+func Exported() { var v t; v.m(); helper() }
+
+func helper() {}
+
+func orphan() {}
+`,
+		"lib/lib_test.go": "package lib\n\nimport \"testing\"\n\nfunc TestX(t *testing.T) { testOnly() }\n\nfunc testOnly() {}\n",
+		// 呼び出さずに値として渡した関数やメソッド値は参照元から到達できるものとする
+		"callbacks.go": `package main
+
+import "sort"
+
+type server struct{ cb func() }
+
+func (server) handle() {}
+
+var table = map[string]func(){"a": tableFn}
+
+func tableFn() {}
+
+func register(f func()) { f() }
+
+func init() {
+	register(callback)
+	xs := []int{2, 1}
+	sort.Slice(xs, less)
+	s := server{cb: fieldFn}
+	register(s.handle)
+}
+
+func callback() {}
+
+func less(i, j int) bool { return i < j }
+
+func fieldFn() {}
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+	ctx := context.Background()
+	modules, err := gomod.Scan(ctx, root, gomod.ScanOptions{})
+	require.NoError(t, err)
+
+	names := func(mods []graph.DeadModule) []string {
+		var result []string
+		for _, m := range mods {
+			assert.Equal(t, "example.com/d", m.Module)
+			for _, p := range m.Packages {
+				for _, n := range p.Functions {
+					result = append(result, n.Name)
+				}
+			}
+		}
+		return result
+	}
+
+	// 既定のルート (main, init, test, exported) と //deadcode:ignore から到達できない関数を定義位置の順に返す
+	// 変数の初期化式からの呼び出しは init からの呼び出しとする
+	_, dead, err := callgraph.DeadCode(ctx, *modules, callgraph.DefaultDeadCodeRoots)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"example.com/d.unused", "example.com/d/lib.t#n", "example.com/d/lib.orphan"}, names(dead))
+	require.Len(t, dead[0].Packages, 2)
+	assert.Equal(t, graph.DeadPackage{
+		Package:   "example.com/d",
+		Dir:       root,
+		Functions: dead[0].Packages[0].Functions,
+	}, dead[0].Packages[0])
+	assert.Equal(t, &graph.Position{File: filepath.Join(root, "main.go"), Line: 11, Column: 6}, dead[0].Packages[0].Functions[0].Pos)
+
+	// ルートを関数で指定する (//deadcode:ignore を付けた関数は常にルートとする)
+	_, dead, err = callgraph.DeadCode(ctx, *modules, []string{"example.com/d/lib.helper"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"example.com/d.server#handle", "example.com/d.tableFn", "example.com/d.register", "example.com/d.init",
+		"example.com/d.callback", "example.com/d.less", "example.com/d.fieldFn",
+		"example.com/d.main", "example.com/d.viaVar", "example.com/d.unused",
+		"example.com/d/lib.t#m", "example.com/d/lib.t#n", "example.com/d/lib.Exported", "example.com/d/lib.orphan",
+		"example.com/d/lib.TestX", "example.com/d/lib.testOnly",
+	}, names(dead))
+
+	_, _, err = callgraph.DeadCode(ctx, *modules, []string{"unknown"})
+	assert.Error(t, err)
+}
+
+func TestHotspots(t *testing.T) {
+	root := t.TempDir()
+	src := `package d
+
+func Target() {}
+
+func A() { Target() }
+
+func B() { Target(); A() }
+
+func C() { B() }
+
+func Other() { C() }
+
+func Leaf() {}
+`
+	require.NoError(t, os.WriteFile(filepath.Join(root, "go.mod"), []byte("module example.com/d\n\ngo 1.24\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "d.go"), []byte(src), 0644))
+	ctx := context.Background()
+	modules, err := gomod.Scan(ctx, root, gomod.ScanOptions{})
+	require.NoError(t, err)
+	mod, ok := modules.FindByPackage("example.com/d")
+	require.True(t, ok)
+
+	type row struct {
+		Name                                   string
+		Depth, Direct, Transitive, EntryPoints int
+	}
+	rows := func(stats []graph.NodeStats) []row {
+		var result []row
+		for _, s := range stats {
+			result = append(result, row{s.Node.Name, s.Depth, s.DirectCallers, s.TransitiveCallers, s.EntryPoints})
+		}
+		return result
+	}
+
+	// 呼び出し元のグラフのノードごとの統計を深さ、ID の順に返す
+	g, err := callgraph.CallersGraph(ctx, *mod, "example.com/d.Target", *modules, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []row{
+		{"example.com/d.Target", 0, 2, 4, 1},
+		{"example.com/d.A", 1, 1, 3, 1},
+		{"example.com/d.B", 1, 1, 2, 1},
+		{"example.com/d.C", 2, 1, 1, 1},
+		{"example.com/d.Other", 3, 0, 0, 1},
+	}, rows(callgraph.NodeStats(g, []string{g.Root}, 0)))
+
+	// 最大深度に達したノードはエントリポイントとして数えない
+	g, err = callgraph.CallersGraph(ctx, *mod, "example.com/d.Target", *modules, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []row{
+		{"example.com/d.Target", 0, 2, 2, 0},
+		{"example.com/d.A", 1, 0, 0, 0},
+		{"example.com/d.B", 1, 0, 0, 0},
+	}, rows(callgraph.NodeStats(g, []string{g.Root}, 1)))
+
+	// 複数の target の呼び出し元をまとめ、指標の降順 (同じ場合は推移的な呼び出し元の数の降順) に上位を返す
+	targets := []symbol.Function{{PkgPath: "example.com/d", Name: "Target"}, {PkgPath: "example.com/d", Name: "A"}}
+	_, hot, err := callgraph.Hotspots(ctx, targets, *modules, callgraph.MetricCallers, 3, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []row{
+		{"example.com/d.Target", 0, 2, 4, 1},
+		{"example.com/d.A", 0, 1, 3, 1},
+		{"example.com/d.B", 1, 1, 2, 1},
+	}, rows(hot))
+
+	// target を省略した場合はワークスペース全体の関数を対象とし、深さは -1 とする
+	_, hot, err = callgraph.Hotspots(ctx, nil, *modules, callgraph.MetricEntryPoints, 0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []row{
+		{"example.com/d.Target", -1, 2, 4, 1},
+		{"example.com/d.A", -1, 1, 3, 1},
+		{"example.com/d.B", -1, 1, 2, 1},
+		{"example.com/d.C", -1, 1, 1, 1},
+		{"example.com/d.Leaf", -1, 0, 0, 1},
+		{"example.com/d.Other", -1, 0, 0, 1},
+	}, rows(hot))
+
+	_, _, err = callgraph.Hotspots(ctx, nil, *modules, "unknown", 0, 0)
+	assert.Error(t, err)
+}
+
+func TestNodeStats_Cycle(t *testing.T) {
+	g := graph.New(&graph.Node{ID: "T", Name: "T"})
+	for _, id := range []string{"A", "B", "C", "D", "E"} {
+		g.AddNode(&graph.Node{ID: id, Name: id})
+	}
+	// T <- A <- B <- A (相互再帰), T <- D <- D (自己再帰), B <- C, D <- C, D <- E
+	g.AddEdge("A", "T")
+	g.AddEdge("B", "A")
+	g.AddEdge("A", "B")
+	g.AddEdge("D", "T")
+	g.AddEdge("D", "D")
+	g.AddEdge("C", "B")
+	g.AddEdge("C", "D")
+	g.AddEdge("E", "D")
+
+	type row struct {
+		Name                                   string
+		Depth, Direct, Transitive, EntryPoints int
+	}
+	var rows []row
+	for _, s := range callgraph.NodeStats(g, []string{g.Root}, 0) {
+		rows = append(rows, row{s.Node.Name, s.Depth, s.DirectCallers, s.TransitiveCallers, s.EntryPoints})
+	}
+	// 循環の他のメンバーは推移的な呼び出し元に含め、自身と共通の呼び出し元は1度だけ数える
+	assert.Equal(t, []row{
+		{"T", 0, 2, 5, 2},
+		{"A", 1, 1, 2, 1},
+		{"D", 1, 2, 2, 2},
+		{"B", 2, 2, 2, 1},
+		{"C", 2, 0, 0, 1},
+		{"E", 2, 0, 0, 1},
+	}, rows)
+}
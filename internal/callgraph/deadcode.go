@@ -0,0 +1,215 @@
+package callgraph
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/meian/rev-callgraph/internal/astquery"
+	"github.com/meian/rev-callgraph/internal/gomod"
+	"github.com/meian/rev-callgraph/internal/graph"
+	"github.com/meian/rev-callgraph/internal/grep"
+	"github.com/meian/rev-callgraph/internal/progress"
+	"github.com/meian/rev-callgraph/internal/stats"
+	"github.com/meian/rev-callgraph/internal/symbol"
+)
+
+// IgnoreDirective は到達できなくても報告しない関数/メソッドのドキュメントコメントに付けるディレクティブです
+// 付けた関数はルートとして扱い、その呼び出し先も到達できるものとします
+const IgnoreDirective = "deadcode:ignore"
+
+// DefaultDeadCodeRoots は DeadCode の既定のルートです
+var DefaultDeadCodeRoots = []string{
+	string(graph.EntryMain), string(graph.EntryInit), string(graph.EntryTest), string(graph.EntryExported),
+}
+
+// DeadCode はワークスペース全体のコールグラフを構築し、roots から到達できない関数/メソッドを
+// モジュール、パッケージごとにまとめて返します
+// roots にはエントリポイントの種別 (main, init, test, handler, exported) または関数名を指定します
+// IgnoreDirective を付けた関数/メソッドはルートとして扱います
+// vendored モジュールは対象外です
+// 一部のファイルのみから求めた結果は到達できる関数も含むため、コンテキストが中断された場合は結果を返さずエラーを返します
+func DeadCode(ctx context.Context, mods gomod.ModuleMap, roots []string) (*graph.Graph, []graph.DeadModule, error) {
+	kinds := make(map[graph.EntryKind]bool)
+	funcs := make(map[string]bool)
+	for _, r := range roots {
+		if k := graph.EntryKind(r); k != graph.EntryNoCallers && slices.Contains(entryOrder, k) {
+			kinds[k] = true
+			continue
+		}
+		if _, err := symbol.ParseFunction(r); err != nil {
+			return nil, nil, fmt.Errorf("ルートの指定が不正です (main, init, test, handler, exported または関数名): %s", r)
+		}
+		funcs[r] = true
+	}
+
+	g, ignored, err := workspaceGraph(ctx, mods, true)
+	if err != nil {
+		return nil, nil, err
+	}
+	for f := range funcs {
+		if _, ok := g.Node(f); !ok {
+			progress.Msgf(ctx, "root %s not found in workspace", f)
+		}
+	}
+
+	// ルートから呼び出し先を幅優先で辿る
+	reached := make(map[string]bool)
+	var queue []string
+	for _, n := range g.Nodes {
+		if kinds[entryKind(n)] || funcs[n.ID] || ignored[n.ID] {
+			reached[n.ID] = true
+			queue = append(queue, n.ID)
+		}
+	}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for _, c := range g.Callees(cur) {
+			if !reached[c.ID] {
+				reached[c.ID] = true
+				queue = append(queue, c.ID)
+			}
+		}
+	}
+	progress.Msgf(ctx, "%d of %d functions reachable", len(reached), len(g.Nodes))
+
+	var dead []*graph.Node
+	for _, n := range g.Nodes {
+		if !reached[n.ID] {
+			dead = append(dead, n)
+		}
+	}
+	return g, groupDead(dead), nil
+}
+
+// workspaceGraph はワークスペースの vendored でないモジュールの全ての関数/メソッドをノードとし、
+// 呼び出しをエッジ (呼び出し箇所を含む) とするグラフを構築します
+// 呼び出しは ExtractCallers と同じ規則で解決し、x.f() は名前が f の全てのメソッドへのエッジとします
+// refs が true の場合は、呼び出さずに参照した関数/メソッド (コールバックとして渡した関数やメソッド値 x.f) への
+// エッジも同じ規則で追加します
+// ワークスペース内に定義がない呼び出し先 (標準ライブラリ、組み込み関数など) は含めません
+// IgnoreDirective を付けた関数/メソッドのノードIDも返します
+func workspaceGraph(ctx context.Context, mods gomod.ModuleMap, refs bool) (*graph.Graph, map[string]bool, error) {
+	defer stats.Start(ctx, stats.PhaseGraph, "workspace")()
+	var files []string
+	for _, mod := range mods.Sorted() {
+		modFiles, err := grep.GoFiles(ctx, mod.Root)
+		if err != nil {
+			return nil, nil, fmt.Errorf("ファイルの走査失敗 %s: %w", mod.Root, err)
+		}
+		// 入れ子のモジュールのファイルはそのモジュールで扱う
+		for _, f := range modFiles {
+			if owner, ok := moduleOfFile(f, mods); ok && owner.Root == mod.Root {
+				files = append(files, f)
+			}
+		}
+	}
+	decls, err := astquery.ExtractDecls(ctx, files, mods)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	g := graph.New(nil)
+	g.Forward = true
+	ignored := make(map[string]bool)
+	methods := make(map[string][]string)
+	for _, d := range decls {
+		mod, _ := moduleOfFile(d.Pos.Filename, mods)
+		n := &graph.Node{
+			ID:     d.String(),
+			Name:   d.String(),
+			Kind:   graph.KindFunc,
+			Module: mod.Path,
+			Pos:    &graph.Position{File: d.Pos.Filename, Line: d.Pos.Line, Column: d.Pos.Column},
+			Main:   d.Main,
+		}
+		if d.IsMethod() {
+			n.Kind = graph.KindMethod
+		}
+		// 同じ名前の宣言 (複数の init 関数やビルドタグ違いのファイル) は1つのノードにまとめる
+		if _, added := g.AddNode(n); added && d.IsMethod() {
+			methods[d.Name] = append(methods[d.Name], n.ID)
+		}
+		if slices.Contains(d.Directives, IgnoreDirective) {
+			ignored[n.ID] = true
+		}
+	}
+	for _, d := range decls {
+		caller := d.String()
+		calls := d.Calls
+		if refs {
+			calls = append(slices.Clip(calls), d.Refs...)
+		}
+		for _, c := range calls {
+			callees := methods[c.Method]
+			if c.Method == "" {
+				callees = []string{c.Function.String()}
+			}
+			for _, callee := range callees {
+				if _, ok := g.Node(callee); !ok {
+					continue
+				}
+				g.AddEdge(caller, callee)
+				g.AddSite(caller, callee, graph.Position{File: c.Pos.Filename, Line: c.Pos.Line, Column: c.Pos.Column})
+			}
+		}
+	}
+	stats.Add(ctx, stats.Nodes, len(g.Nodes))
+	stats.Add(ctx, stats.Edges, len(g.Edges))
+	return g, ignored, nil
+}
+
+// moduleOfFile は file を含むモジュールのうち、ルートディレクトリが最も深いものを返します
+// vendored モジュールは対象外です
+func moduleOfFile(file string, mods gomod.ModuleMap) (gomod.Module, bool) {
+	var (
+		best  gomod.Module
+		found bool
+	)
+	for _, m := range mods.Iter {
+		if m.IsVendored() {
+			continue
+		}
+		rel, err := filepath.Rel(m.Root, filepath.Dir(file))
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		if !found || len(m.Root) > len(best.Root) {
+			best, found = m, true
+		}
+	}
+	return best, found
+}
+
+// groupDead は到達できない関数をモジュール、パッケージごとにまとめます
+// モジュールはパスの順、パッケージはディレクトリの順、関数は定義位置の順に並べます
+func groupDead(dead []*graph.Node) []graph.DeadModule {
+	slices.SortFunc(dead, func(a, b *graph.Node) int {
+		return cmp.Or(
+			strings.Compare(a.Module, b.Module),
+			strings.Compare(filepath.Dir(a.Pos.File), filepath.Dir(b.Pos.File)),
+			strings.Compare(a.Pos.File, b.Pos.File),
+			cmp.Compare(a.Pos.Line, b.Pos.Line),
+			cmp.Compare(a.Pos.Column, b.Pos.Column),
+		)
+	})
+	var result []graph.DeadModule
+	for _, n := range dead {
+		f, _ := symbol.ParseFunction(n.Name)
+		if len(result) == 0 || result[len(result)-1].Module != n.Module {
+			result = append(result, graph.DeadModule{Module: n.Module})
+		}
+		m := &result[len(result)-1]
+		dir := filepath.Dir(n.Pos.File)
+		if len(m.Packages) == 0 || m.Packages[len(m.Packages)-1].Dir != dir {
+			m.Packages = append(m.Packages, graph.DeadPackage{Package: f.PkgPath, Dir: dir})
+		}
+		p := &m.Packages[len(m.Packages)-1]
+		p.Functions = append(p.Functions, n)
+	}
+	return result
+}
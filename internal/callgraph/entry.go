@@ -0,0 +1,143 @@
+package callgraph
+
+import (
+	"cmp"
+	"context"
+	"go/token"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/meian/rev-callgraph/internal/gomod"
+	"github.com/meian/rev-callgraph/internal/graph"
+	"github.com/meian/rev-callgraph/internal/symbol"
+)
+
+// testPrefixes は go test が実行する関数名の接頭辞です
+var testPrefixes = []string{"Test", "Benchmark", "Fuzz", "Example"}
+
+// entryOrder はエントリポイントを出力する種別の順序です
+var entryOrder = []graph.EntryKind{
+	graph.EntryMain, graph.EntryInit, graph.EntryTest, graph.EntryHandler, graph.EntryExported, graph.EntryNoCallers,
+}
+
+// entryKind はノードのエントリポイントとしての種別を返します
+// いずれにも該当しない場合は空文字列を返します (呼び出し元の有無は判定しません)
+func entryKind(n *graph.Node) graph.EntryKind {
+	f, err := symbol.ParseFunction(n.Name)
+	if err != nil {
+		return ""
+	}
+	inTest := n.Pos != nil && strings.HasSuffix(n.Pos.File, "_test.go")
+	switch {
+	case n.Main && !f.IsMethod() && f.Name == "main":
+		return graph.EntryMain
+	case !f.IsMethod() && f.Name == "init":
+		return graph.EntryInit
+	case inTest && !f.IsMethod() && slices.ContainsFunc(testPrefixes, func(p string) bool {
+		return isTestName(f.Name, p)
+	}):
+		return graph.EntryTest
+	case f.IsMethod() && f.Name == "ServeHTTP":
+		return graph.EntryHandler
+	case !n.Main && !inTest && token.IsExported(f.Name) && (!f.IsMethod() || token.IsExported(f.TypeName)):
+		return graph.EntryExported
+	}
+	return ""
+}
+
+// isTestName は name が go test の実行する prefix の関数名かどうかを判定します
+// go test と同様に、prefix と一致するか prefix の直後が小文字以外の場合に限ります (Testimony などは除く)
+func isTestName(name, prefix string) bool {
+	rest, ok := strings.CutPrefix(name, prefix)
+	if !ok {
+		return false
+	}
+	if rest == "" {
+		return true
+	}
+	r, _ := utf8.DecodeRuneInString(rest)
+	return !unicode.IsLower(r)
+}
+
+// isEntryPoint はノードが実行の起点となる関数かどうかを判定します
+// main パッケージの main 関数、_test.go ファイル内のテスト関数、HTTP ハンドラ (ServeHTTP メソッド) を起点とします
+func isEntryPoint(n *graph.Node) bool {
+	switch entryKind(n) {
+	case graph.EntryMain, graph.EntryTest, graph.EntryHandler:
+		return true
+	}
+	return false
+}
+
+// EntryPoints は target の呼び出し元のグラフを構築し、呼び出し元のない末端のノードを
+// target に到達するエントリポイントとして分類して返します
+// 末端のノードは main 関数、init 関数、テスト関数、HTTP ハンドラ、main 以外のパッケージの公開 API、
+// それ以外 (呼び出し元なし) に分類し、種別ごとに名前順で返します
+// 各エントリポイントには target に至る最短の経路を一例として付与します
+// 探索を打ち切ったノードは呼び出し元の有無が分からないため含めません
+// maxDepth=0の場合は無制限
+// コンテキストが中断された場合は、それまでに構築したグラフから分類した結果と ctx.Err() を返す
+func EntryPoints(ctx context.Context, mod gomod.Module, target string, mods gomod.ModuleMap, maxDepth int) (*graph.Graph, []graph.EntryPoint, error) {
+	g, err := CallersGraph(ctx, mod, target, mods, maxDepth)
+	if g == nil {
+		return nil, nil, err
+	}
+	// 深さの判定と経路の例に使う最短経路は、起点ノードからの1度の探索でまとめて求める
+	shortest := g.ShortestPathsTo(g.Root)
+	var eps []graph.EntryPoint
+	for _, n := range g.Nodes {
+		if !isLeaf(g, n) || n.Truncated != "" {
+			continue
+		}
+		path := shortest(n.ID)
+		if atMaxDepth(path, maxDepth) {
+			continue
+		}
+		kind := entryKind(n)
+		if kind == "" {
+			kind = graph.EntryNoCallers
+		}
+		eps = append(eps, graph.EntryPoint{Node: n, Kind: kind, Dir: packageDir(n, mods), Path: path})
+	}
+	slices.SortStableFunc(eps, func(a, b graph.EntryPoint) int {
+		return cmp.Or(
+			cmp.Compare(slices.Index(entryOrder, a.Kind), slices.Index(entryOrder, b.Kind)),
+			strings.Compare(a.Node.ID, b.Node.ID),
+		)
+	})
+	return g, eps, err
+}
+
+// isLeaf はノードに自身以外の呼び出し元がないかどうかを判定します
+// 自身のみから呼ばれる再帰関数も末端とします
+func isLeaf(g *graph.Graph, n *graph.Node) bool {
+	return !slices.ContainsFunc(g.Callers(n.ID), func(c *graph.Node) bool {
+		return c.ID != n.ID
+	})
+}
+
+// atMaxDepth は起点ノードまでの最短の経路が path のノードが、最大深さに達して展開されなかったかどうかを判定します
+func atMaxDepth(path graph.Path, maxDepth int) bool {
+	return maxDepth > 0 && path.Depth() >= maxDepth
+}
+
+// packageDir はノードを含むパッケージのディレクトリを返します
+// 定義位置が分からない場合はモジュールのパッケージディレクトリを返します
+func packageDir(n *graph.Node, mods gomod.ModuleMap) string {
+	if n.Pos != nil {
+		return filepath.Dir(n.Pos.File)
+	}
+	f, err := symbol.ParseFunction(n.Name)
+	if err != nil {
+		return ""
+	}
+	mod, ok := mods.FindByPackage(f.PkgPath)
+	if !ok {
+		return ""
+	}
+	dir, _ := mod.PackageDir(f.PkgPath)
+	return dir
+}
@@ -0,0 +1,37 @@
+package callgraph
+
+import (
+	"testing"
+
+	"github.com/meian/rev-callgraph/internal/graph"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEntryKind_Test(t *testing.T) {
+	tests := []struct {
+		name string
+		want graph.EntryKind
+	}{
+		{"Test", graph.EntryTest},
+		{"TestFoo", graph.EntryTest},
+		{"Test_foo", graph.EntryTest},
+		{"Test1", graph.EntryTest},
+		{"BenchmarkFoo", graph.EntryTest},
+		{"FuzzFoo", graph.EntryTest},
+		{"Example", graph.EntryTest},
+		{"Example_foo", graph.EntryTest},
+		{"ExampleFoo_bar", graph.EntryTest},
+		// 接頭辞の直後が小文字の関数は go test が実行しない
+		{"Testimony", ""},
+		{"Benchmarker", ""},
+		{"Fuzzy", ""},
+		{"Examplefoo", ""},
+		{"testFoo", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := &graph.Node{Name: "example.com/p." + tt.name, Pos: &graph.Position{File: "/src/p/p_test.go", Line: 1, Column: 1}}
+			assert.Equal(t, tt.want, entryKind(n))
+		})
+	}
+}
@@ -0,0 +1,254 @@
+package callgraph
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"math/bits"
+	"slices"
+	"strings"
+
+	"github.com/meian/rev-callgraph/internal/gomod"
+	"github.com/meian/rev-callgraph/internal/graph"
+	"github.com/meian/rev-callgraph/internal/progress"
+	"github.com/meian/rev-callgraph/internal/symbol"
+)
+
+// HotspotMetric は Hotspots の順位付けに使う指標です
+type HotspotMetric string
+
+const (
+	// MetricCallers は直接の呼び出し元の数で順位付けします
+	MetricCallers HotspotMetric = "callers"
+	// MetricTransitive は推移的な呼び出し元の数で順位付けします
+	MetricTransitive HotspotMetric = "transitive"
+	// MetricEntryPoints は到達するエントリポイントの数で順位付けします
+	MetricEntryPoints HotspotMetric = "entrypoints"
+)
+
+// NodeStats はグラフ g の各ノードについて、直接・推移的な呼び出し元の数、到達するエントリポイントの数、
+// roots からの最短距離を求め、距離、ID の順に返します
+// エントリポイントは EntryPoints と同様に呼び出し元のない末端のノードとし、
+// 探索を打ち切ったノード (中断されたノードと maxDepth に達したノード) は含めません
+// roots が空の場合、距離は -1 とします
+//
+// 推移的な呼び出し元は強連結成分を縮約したグラフを呼び出し元から順に辿り、成分ごとに1度だけ求めます
+// ノード数 N、成分数 C、エッジ数 E に対して計算量は O(E + C·N/64) で、
+// 呼び出し先の成分が未処理の成分ごとに N ビットの集合を保持します
+func NodeStats(g *graph.Graph, roots []string, maxDepth int) []graph.NodeStats {
+	depth := callerDepths(g, roots)
+	entry := newBitset(len(g.Nodes))
+	for i, n := range g.Nodes {
+		d, ok := depth[n.ID]
+		atMax := maxDepth > 0 && ok && d >= maxDepth
+		if isLeaf(g, n) && n.Truncated == "" && !atMax {
+			entry.add(i)
+		}
+	}
+	callers := transitiveCallers(g)
+
+	result := make([]graph.NodeStats, 0, len(g.Nodes))
+	for _, n := range g.Nodes {
+		s := graph.NodeStats{Node: n, Depth: -1}
+		if d, ok := depth[n.ID]; ok {
+			s.Depth = d
+		}
+		for _, c := range g.Callers(n.ID) {
+			if c.ID != n.ID {
+				s.DirectCallers++
+			}
+		}
+		// 集合は自身を含むため、推移的な呼び出し元の数からは除く
+		set := callers[n.ID]
+		s.TransitiveCallers = set.count() - 1
+		s.EntryPoints = set.countAnd(entry)
+		result = append(result, s)
+	}
+	slices.SortStableFunc(result, func(a, b graph.NodeStats) int {
+		return cmp.Or(cmp.Compare(a.Depth, b.Depth), strings.Compare(a.Node.ID, b.Node.ID))
+	})
+	return result
+}
+
+// transitiveCallers は各ノードについて、自身と呼び出し元を辿って到達できるノードの集合を返します
+// 集合は g.Nodes の添字で表し、同じ強連結成分のノードは同じ集合を共有します
+func transitiveCallers(g *graph.Graph) map[string]bitset {
+	comp, ncomp := g.Components()
+	members := make([][]int, ncomp+1)
+	for i, n := range g.Nodes {
+		members[comp[n.ID]] = append(members[comp[n.ID]], i)
+	}
+	// 成分の間の呼び出し元と、まだ集合を求めていない呼び出し先の成分の数
+	callers := make([][]int, ncomp+1)
+	pending := make([]int, ncomp+1)
+	seen := make(map[[2]int]bool)
+	for _, e := range g.Edges {
+		caller, callee := comp[e.Caller], comp[e.Callee]
+		if caller == callee || seen[[2]int{caller, callee}] {
+			continue
+		}
+		seen[[2]int{caller, callee}] = true
+		callers[callee] = append(callers[callee], caller)
+		pending[caller]++
+	}
+
+	// 番号の大きい成分ほど呼び出し元側にあるため、降順に辿れば呼び出し元の集合は求め終わっている
+	sets := make([]bitset, ncomp+1)
+	result := make(map[string]bitset, len(g.Nodes))
+	for c := ncomp; c >= 1; c-- {
+		set := newBitset(len(g.Nodes))
+		for _, i := range members[c] {
+			set.add(i)
+		}
+		for _, p := range callers[c] {
+			set.or(sets[p])
+			// 全ての呼び出し先で使い終わった集合は保持しない
+			if pending[p]--; pending[p] == 0 {
+				sets[p] = nil
+			}
+		}
+		if pending[c] > 0 {
+			sets[c] = set
+		}
+		for _, i := range members[c] {
+			result[g.Nodes[i].ID] = set
+		}
+	}
+	return result
+}
+
+// bitset は添字の集合を表します
+type bitset []uint64
+
+// newBitset は n 個の添字を格納できる空の集合を返します
+func newBitset(n int) bitset {
+	return make(bitset, (n+63)/64)
+}
+
+// add は集合に i を加えます
+func (b bitset) add(i int) {
+	b[i/64] |= 1 << (i % 64)
+}
+
+// or は集合に other の要素を加えます
+func (b bitset) or(other bitset) {
+	for i, w := range other {
+		b[i] |= w
+	}
+}
+
+// count は集合の要素数を返します
+func (b bitset) count() int {
+	n := 0
+	for _, w := range b {
+		n += bits.OnesCount64(w)
+	}
+	return n
+}
+
+// countAnd は集合と other の両方に含まれる要素数を返します
+func (b bitset) countAnd(other bitset) int {
+	n := 0
+	for i, w := range b {
+		n += bits.OnesCount64(w & other[i])
+	}
+	return n
+}
+
+// callerDepths は roots から呼び出し元方向に幅優先で辿った各ノードの最短距離を返します
+func callerDepths(g *graph.Graph, roots []string) map[string]int {
+	depth := make(map[string]int)
+	var queue []string
+	for _, r := range roots {
+		if _, ok := depth[r]; !ok {
+			depth[r] = 0
+			queue = append(queue, r)
+		}
+	}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for _, c := range g.Callers(cur) {
+			if _, ok := depth[c.ID]; !ok {
+				depth[c.ID] = depth[cur] + 1
+				queue = append(queue, c.ID)
+			}
+		}
+	}
+	return depth
+}
+
+// Hotspots は targets の呼び出し元をまとめて辿ったグラフ (targets が空の場合はワークスペース全体のコールグラフ) の
+// 各ノードの統計を metric の降順 (同じ場合は推移的な呼び出し元の数の降順、ID の順) に並べ、上位 limit 件を返します
+// limit=0 の場合は全件を返します
+// maxDepth=0の場合は無制限 (ワークスペース全体の場合は使いません)
+// コンテキストが中断された場合は、それまでに構築したグラフから求めた統計と ctx.Err() を返す
+// ただし targets が空の場合はワークスペース全体のグラフを構築できないため、グラフを返さずエラーを返す
+func Hotspots(ctx context.Context, targets []symbol.Function, mods gomod.ModuleMap, metric HotspotMetric, limit, maxDepth int) (*graph.Graph, []graph.NodeStats, error) {
+	value := map[HotspotMetric]func(graph.NodeStats) int{
+		MetricCallers:     func(s graph.NodeStats) int { return s.DirectCallers },
+		MetricTransitive:  func(s graph.NodeStats) int { return s.TransitiveCallers },
+		MetricEntryPoints: func(s graph.NodeStats) int { return s.EntryPoints },
+	}[metric]
+	if value == nil {
+		return nil, nil, fmt.Errorf("順位付けの指標が不正です (callers, transitive, entrypoints): %s", metric)
+	}
+
+	var (
+		g     *graph.Graph
+		roots []string
+		err   error
+	)
+	if len(targets) == 0 {
+		g, _, err = workspaceGraph(ctx, mods, false)
+		maxDepth = 0
+	} else {
+		var vs []vertex
+		vs, err = resolveVertices(ctx, targets, mods)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(vs) == 0 {
+			return nil, nil, fmt.Errorf("定義が見つかる関数がありません")
+		}
+		for _, v := range vs {
+			roots = append(roots, v.id())
+		}
+		g, err = buildGraphFrom(ctx, vs, callersTraversal(ctx, mods, maxDepth))
+	}
+	if g == nil {
+		return nil, nil, err
+	}
+
+	result := NodeStats(g, roots, maxDepth)
+	slices.SortStableFunc(result, func(a, b graph.NodeStats) int {
+		return cmp.Or(
+			cmp.Compare(value(b), value(a)),
+			cmp.Compare(b.TransitiveCallers, a.TransitiveCallers),
+			strings.Compare(a.Node.ID, b.Node.ID),
+		)
+	})
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	progress.Msgf(ctx, "ranked %d of %d functions by %s", len(result), len(g.Nodes), metric)
+	return g, result, err
+}
+
+// resolveVertices は関数の定義を含むモジュールを求め、探索の起点とする頂点を返します
+// 定義が見つからない関数は除外します
+func resolveVertices(ctx context.Context, funcs []symbol.Function, mods gomod.ModuleMap) ([]vertex, error) {
+	var vs []vertex
+	for _, f := range funcs {
+		mod, _, err := mods.LookupDefinition(ctx, f)
+		if err != nil {
+			return nil, fmt.Errorf("定義の確認失敗 %s: %w", f, err)
+		}
+		if mod == nil {
+			progress.Msgf(ctx, "skip %s: definition not found", f)
+			continue
+		}
+		vs = append(vs, vertex{mod: *mod, name: f.String()})
+	}
+	return vs, nil
+}
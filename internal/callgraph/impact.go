@@ -0,0 +1,97 @@
+package callgraph
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/meian/rev-callgraph/internal/gomod"
+	"github.com/meian/rev-callgraph/internal/graph"
+	"github.com/meian/rev-callgraph/internal/symbol"
+)
+
+// Impact は変更された関数 changed の呼び出し元をまとめて逆方向に辿り、影響範囲を返します
+// 呼び出し元のない末端のノードを EntryPoints と同じ規則でエントリポイントとして分類し、
+// 到達できる変更された関数ごとに、その関数に至る最短の経路を付与します
+// 定義が見つからない関数 (削除された関数など) は除外します
+// maxDepth=0の場合は無制限
+// コンテキストが中断された場合は、それまでに構築したグラフから求めた影響範囲と ctx.Err() を返す
+func Impact(ctx context.Context, changed []symbol.Function, mods gomod.ModuleMap, maxDepth int) (*graph.Graph, *graph.Impact, error) {
+	roots, err := resolveVertices(ctx, changed, mods)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(roots) == 0 {
+		return nil, &graph.Impact{}, nil
+	}
+
+	g, err := buildGraphFrom(ctx, roots, callersTraversal(ctx, mods, maxDepth))
+	if g == nil {
+		return nil, nil, err
+	}
+	impact := &graph.Impact{}
+	for _, r := range roots {
+		n, _ := g.Node(r.id())
+		impact.Changed = append(impact.Changed, n)
+	}
+	pathTo := make([]func(string) graph.Path, len(impact.Changed))
+	for i, c := range impact.Changed {
+		pathTo[i] = g.ShortestPathsTo(c.ID)
+	}
+	for _, n := range g.Nodes {
+		if !isLeaf(g, n) || n.Truncated != "" {
+			continue
+		}
+		var entries []graph.ImpactEntry
+		depth := -1
+		for i, c := range impact.Changed {
+			p := pathTo[i](n.ID)
+			if p == nil {
+				continue
+			}
+			if depth < 0 || p.Depth() < depth {
+				depth = p.Depth()
+			}
+			entries = append(entries, graph.ImpactEntry{Changed: c.ID, EntryPoint: graph.EntryPoint{Path: p}})
+		}
+		// 最も近い変更された関数からの距離が最大深さに達したノードは展開されていない
+		if maxDepth > 0 && depth >= maxDepth {
+			continue
+		}
+		kind := entryKind(n)
+		if kind == "" {
+			kind = graph.EntryNoCallers
+		}
+		dir := packageDir(n, mods)
+		for _, e := range entries {
+			e.Node, e.Kind, e.Dir = n, kind, dir
+			impact.EntryPoints = append(impact.EntryPoints, e)
+		}
+		if n.Main && (kind == graph.EntryMain || kind == graph.EntryInit) {
+			if f, perr := symbol.ParseFunction(n.Name); perr == nil && !slices.ContainsFunc(impact.Binaries, func(b graph.Binary) bool {
+				return b.Package == f.PkgPath
+			}) {
+				impact.Binaries = append(impact.Binaries, graph.Binary{Package: f.PkgPath, Dir: dir})
+			}
+		}
+	}
+	slices.SortStableFunc(impact.EntryPoints, func(a, b graph.ImpactEntry) int {
+		return cmp.Or(
+			cmp.Compare(slices.Index(entryOrder, a.Kind), slices.Index(entryOrder, b.Kind)),
+			strings.Compare(a.Node.ID, b.Node.ID),
+			strings.Compare(a.Changed, b.Changed),
+		)
+	})
+	slices.SortFunc(impact.Binaries, func(a, b graph.Binary) int {
+		return strings.Compare(a.Package, b.Package)
+	})
+	for _, n := range g.Nodes {
+		if f, perr := symbol.ParseFunction(n.Name); perr == nil {
+			impact.Packages = append(impact.Packages, f.PkgPath)
+		}
+	}
+	slices.Sort(impact.Packages)
+	impact.Packages = slices.Compact(impact.Packages)
+	return g, impact, err
+}
@@ -0,0 +1,79 @@
+package callgraph
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/meian/rev-callgraph/internal/astquery"
+	"github.com/meian/rev-callgraph/internal/contextutil"
+	"github.com/meian/rev-callgraph/internal/gomod"
+	"github.com/meian/rev-callgraph/internal/graph"
+	"github.com/meian/rev-callgraph/internal/grep"
+	"github.com/meian/rev-callgraph/internal/progress"
+	"github.com/meian/rev-callgraph/internal/stats"
+)
+
+// ImportersGraph は pkg をインポートしているパッケージを再帰的に探索し、グラフとして構築する
+// 各エッジの Caller にはそのパッケージをインポートしているパッケージが格納される
+// maxDepth=0の場合は無制限
+// コンテキストが中断された場合は、それまでに構築したグラフと ctx.Err() を返す
+func ImportersGraph(ctx context.Context, mod gomod.Module, pkg string, mods gomod.ModuleMap, maxDepth int) (*graph.Graph, error) {
+	return buildGraph(ctx, vertex{mod: mod, name: pkg}, traversal{
+		maxDepth: maxDepth,
+		newNode: func(v vertex) *graph.Node {
+			return &graph.Node{
+				ID:     v.id(),
+				Name:   v.name,
+				Kind:   graph.KindPackage,
+				Module: v.mod.Path,
+				Main:   getPkgNameFromPath(ctx, v.name, mods) == "main",
+			}
+		},
+		expand: func(ctx context.Context, v vertex) ([]vertex, error) {
+			return searchImporters(ctx, v.mod, v.name, mods)
+		},
+	})
+}
+
+// searchImporters は mod と mod を参照しているモジュールから pkg をインポートしているパッケージを探索する
+// mod 自身の探索に失敗した場合はエラーを返し、参照元モジュールの失敗はスキップする
+func searchImporters(ctx context.Context, mod gomod.Module, pkg string, mods gomod.ModuleMap) ([]vertex, error) {
+	progress.Msgf(ctx, "search importers for %s in %s", pkg, mod.Path)
+	defer stats.Start(ctx, stats.PhaseTarget, pkg)()
+
+	// 同一モジュールと参照元モジュールを探索
+	var importers []string
+	for _, m := range append([]gomod.Module{mod}, mods.ReferencedBy(mod)...) {
+		if contextutil.IsCanceledOrTimedOut(ctx) {
+			return nil, ctx.Err()
+		}
+		files, err := grep.SearchImports(ctx, m.Root, pkg)
+		if err != nil {
+			if m.Path == mod.Path {
+				return nil, fmt.Errorf("grep.SearchImports失敗: %w", err)
+			}
+			continue // 参照元1つ失敗しても他は続行
+		}
+		list, err := astquery.ExtractImporters(ctx, pkg, files, mods)
+		if err != nil {
+			if m.Path == mod.Path {
+				return nil, fmt.Errorf("AST解析失敗: %w", err)
+			}
+			continue
+		}
+		for _, i := range list {
+			if !slices.Contains(importers, i) {
+				importers = append(importers, i)
+			}
+		}
+	}
+
+	var found []vertex
+	for _, i := range importers {
+		if modOfImporter, ok := mods.FindByPackage(i); ok {
+			found = append(found, vertex{mod: *modOfImporter, name: i})
+		}
+	}
+	return found, nil
+}
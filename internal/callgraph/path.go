@@ -0,0 +1,57 @@
+package callgraph
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/meian/rev-callgraph/internal/gomod"
+	"github.com/meian/rev-callgraph/internal/graph"
+)
+
+// CallPaths は to の呼び出し元を辿って from に至る呼び出しの経路を返します
+// mod は to の定義を含むモジュールです
+// from は vendored のコピーを含め、関数名が一致する全てのノードを経路の始点とします
+// from に到達したノードはそれ以上展開せず、from に到達しない分岐は経路に含めません
+// 経路は from から to への単純な経路 (同じ関数を二度通らない経路) で、limit>0 の場合は最初の limit 件で打ち切り、
+// limit 件の経路が見つかった深さで探索を終了します
+// from と to が同じ関数の場合はエラーを返します
+// maxDepth=0の場合は無制限
+// コンテキストが中断された場合は、それまでに構築したグラフ上の経路と ctx.Err() を返す
+func CallPaths(ctx context.Context, mod gomod.Module, from, to string, mods gomod.ModuleMap, limit, maxDepth int) (*graph.Graph, []graph.Path, error) {
+	if from == to {
+		return nil, nil, fmt.Errorf("経路の始点と終点に同じ関数が指定されています: %s", from)
+	}
+	t := callersTraversal(ctx, mods, maxDepth)
+	t.leaf = func(n *graph.Node) bool {
+		return n.Name == from
+	}
+	if limit > 0 {
+		t.done = func(g *graph.Graph) bool {
+			return len(pathsFrom(g, from, limit)) >= limit
+		}
+	}
+	g, err := buildGraph(ctx, vertex{mod: mod, name: to}, t)
+	if g == nil {
+		return nil, nil, err
+	}
+	return g, pathsFrom(g, from, limit), err
+}
+
+// pathsFrom は関数名が from のノードから起点ノードに至る単純な経路を、ノードが追加された順に返します
+// limit>0 の場合は最初の limit 件で打ち切ります
+func pathsFrom(g *graph.Graph, from string, limit int) []graph.Path {
+	var paths []graph.Path
+	for _, n := range g.Nodes {
+		if n.Name != from {
+			continue
+		}
+		rest := 0
+		if limit > 0 {
+			if rest = limit - len(paths); rest <= 0 {
+				break
+			}
+		}
+		paths = append(paths, g.Paths(n.ID, rest)...)
+	}
+	return paths
+}
@@ -0,0 +1,47 @@
+package callgraph
+
+import (
+	"context"
+
+	"github.com/meian/rev-callgraph/internal/gomod"
+	"github.com/meian/rev-callgraph/internal/graph"
+)
+
+// ShortestCallers は target の呼び出し元を幅優先で辿り、target に近い起点 (main, テスト, HTTP ハンドラ) から
+// target に至る最短の経路を近い順に最大 k 件返します
+// 起点に到達したノードはそれ以上展開せず、k 件の起点が見つかった深さで探索を終了します
+// 返すグラフは探索した範囲のノードとエッジ (呼び出し箇所を含む) を保持します
+// maxDepth=0の場合は無制限
+// コンテキストが中断された場合は、それまでに見つかった経路と ctx.Err() を返す
+func ShortestCallers(ctx context.Context, mod gomod.Module, target string, mods gomod.ModuleMap, k, maxDepth int) (*graph.Graph, []graph.Path, error) {
+	t := callersTraversal(ctx, mods, maxDepth)
+	t.leaf = isEntryPoint
+	t.done = func(g *graph.Graph) bool {
+		return len(entryPoints(g, k)) >= k
+	}
+	g, err := buildGraph(ctx, vertex{mod: mod, name: target}, t)
+	if g == nil {
+		return nil, nil, err
+	}
+	// 各起点から target への最短経路は target からの1度の探索でまとめて求める
+	shortest := g.ShortestPathsTo(g.Root)
+	var paths []graph.Path
+	for _, n := range entryPoints(g, k) {
+		paths = append(paths, shortest(n.ID))
+	}
+	return g, paths, err
+}
+
+// entryPoints はグラフ内の起点ノードを、追加された順 (起点ノードから近い順) に最大 k 件返します
+func entryPoints(g *graph.Graph, k int) []*graph.Node {
+	var nodes []*graph.Node
+	for _, n := range g.Nodes {
+		if len(nodes) >= k {
+			break
+		}
+		if isEntryPoint(n) {
+			nodes = append(nodes, n)
+		}
+	}
+	return nodes
+}
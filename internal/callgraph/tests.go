@@ -0,0 +1,71 @@
+package callgraph
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/meian/rev-callgraph/internal/gomod"
+	"github.com/meian/rev-callgraph/internal/graph"
+	"github.com/meian/rev-callgraph/internal/symbol"
+)
+
+// AffectedTests はグラフ g に含まれるテスト関数 (起点ノードから呼び出し元を辿って到達したもの) を
+// パッケージディレクトリごとにまとめ、ディレクトリ順に返します
+// dir はワークスペースのルートディレクトリで、モジュールのルートの相対パスの基準とします
+// vendored モジュール内のテスト関数は go test で実行できないため含みません
+func AffectedTests(g *graph.Graph, mods gomod.ModuleMap, dir string) []graph.TestPackage {
+	byDir := make(map[string]*graph.TestPackage)
+	for _, n := range g.Nodes {
+		if n.Vendor != "" || entryKind(n) != graph.EntryTest {
+			continue
+		}
+		f, err := symbol.ParseFunction(n.Name)
+		if err != nil {
+			continue
+		}
+		mod, ok := mods.FindByPackage(f.PkgPath)
+		if !ok {
+			continue
+		}
+		pkgDir := packageDir(n, mods)
+		p, ok := byDir[pkgDir]
+		if !ok {
+			p = &graph.TestPackage{
+				Package:   f.PkgPath,
+				Dir:       pkgDir,
+				ModuleDir: relPath(dir, mod.Root),
+				Pattern:   "./" + relPath(mod.Root, pkgDir),
+			}
+			if p.Pattern == "./." {
+				p.Pattern = "."
+			}
+			byDir[pkgDir] = p
+		}
+		if strings.HasPrefix(f.Name, "Benchmark") {
+			p.Benchmarks = append(p.Benchmarks, f.Name)
+		} else {
+			p.Tests = append(p.Tests, f.Name)
+		}
+	}
+	pkgs := make([]graph.TestPackage, 0, len(byDir))
+	for _, p := range byDir {
+		slices.Sort(p.Tests)
+		slices.Sort(p.Benchmarks)
+		pkgs = append(pkgs, *p)
+	}
+	slices.SortFunc(pkgs, func(a, b graph.TestPackage) int {
+		return strings.Compare(a.Dir, b.Dir)
+	})
+	return pkgs
+}
+
+// relPath は base から target への相対パスをスラッシュ区切りで返します
+// 相対パスにできない場合は target をそのまま返します
+func relPath(base, target string) string {
+	rel, err := filepath.Rel(base, target)
+	if err != nil {
+		return target
+	}
+	return filepath.ToSlash(rel)
+}
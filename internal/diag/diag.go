@@ -0,0 +1,58 @@
+// Package diag は実行を中断せずに続行したファイル単位の問題 (診断) を報告します
+package diag
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Reporter は診断を警告として出力するための構造体です。
+// 複数のゴルーチンから同時に呼び出せ、同じファイルの同じ診断は一度だけ出力します。
+type Reporter struct {
+	mu   sync.Mutex
+	w    io.Writer
+	seen map[string]struct{}
+}
+
+// NewReporter は w に診断を出力する Reporter を返します。
+func NewReporter(w io.Writer) *Reporter {
+	return &Reporter{w: w, seen: make(map[string]struct{})}
+}
+
+// Report は path についての診断 msg を出力します。
+func (r *Reporter) Report(path, msg string) {
+	line := fmt.Sprintf("警告: %s: %s", path, msg)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.seen[line]; ok {
+		return
+	}
+	r.seen[line] = struct{}{}
+	fmt.Fprintln(r.w, line)
+}
+
+// Count は出力した診断の数を返します。
+func (r *Reporter) Count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.seen)
+}
+
+type reporterKey struct{}
+
+// WithReporter はコンテキストに Reporter を追加します。
+func WithReporter(ctx context.Context, r *Reporter) context.Context {
+	return context.WithValue(ctx, reporterKey{}, r)
+}
+
+// Warnf はコンテキストに紐づけられた Reporter に path についての診断を出力します。
+// Reporter が存在しない場合は何もしません。
+func Warnf(ctx context.Context, path, format string, args ...any) {
+	r, ok := ctx.Value(reporterKey{}).(*Reporter)
+	if !ok || r == nil {
+		return
+	}
+	r.Report(path, fmt.Sprintf(format, args...))
+}
@@ -1,16 +1,161 @@
 package format
 
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/meian/rev-callgraph/internal/graph"
+)
+
 // dotPrinter はdot形式でコールグラフを出力するプリンタです。
-// type dotPrinter struct{}
-
-// func init() {
-// 	printers["dot"] = func(string) Printer {
-// 		return &dotPrinter{}
-// 	}
-// }
-
-// Print はコールグラフをdot形式で出力します。
-// func (p *dotPrinter) Print(root *symbol.CallNode) error {
-// 	fmt.Println("[dot出力は未実装]")
-// 	return nil
-// }
+type dotPrinter struct{}
+
+func init() {
+	printers["dot"] = func(string, int) Printer {
+		return &dotPrinter{}
+	}
+	p := &dotPrinter{}
+	registerReport("dot", p.PrintPaths)
+	registerReport("dot", p.PrintEntryPoints)
+	registerReport("dot", p.PrintImpact)
+	registerReport("dot", p.PrintCycles)
+}
+
+// Print はコールグラフをGraphvizのdot形式で出力します。
+// 起点ノードは太字、mainパッケージのノードは箱型、vendored のノードは破線、ワークスペース外のノードは灰色で描画し、
+// 循環に含まれるノードは二重線、探索が中断されたノードは赤色にします。
+func (p *dotPrinter) Print(g *graph.Graph) error {
+	if g == nil {
+		return nil
+	}
+	w := bufio.NewWriter(os.Stdout)
+	fmt.Fprintln(w, "digraph callgraph {")
+	for _, n := range g.Nodes {
+		writeDotNode(w, g, n)
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(w, "  %s -> %s;\n", strconv.Quote(e.Caller), strconv.Quote(e.Callee))
+	}
+	fmt.Fprintln(w, "}")
+	return w.Flush()
+}
+
+// PrintPaths は経路に含まれるノードとエッジをdot形式で出力します。
+// エッジには呼び出し箇所のファイル名と行番号をラベルとして付けます。
+func (p *dotPrinter) PrintPaths(r Paths) error {
+	g, paths := r.Graph, r.Paths
+	w := bufio.NewWriter(os.Stdout)
+	fmt.Fprintln(w, "digraph callgraph {")
+	nodes := make(map[string]struct{})
+	for _, path := range paths {
+		for _, id := range path {
+			if _, ok := nodes[id]; ok {
+				continue
+			}
+			nodes[id] = struct{}{}
+			n, _ := g.Node(id)
+			writeDotNode(w, g, n)
+		}
+	}
+	edges := make(map[graph.Edge]struct{})
+	for _, path := range paths {
+		for j := 1; j < len(path); j++ {
+			e := graph.Edge{Caller: path[j-1], Callee: path[j]}
+			if _, ok := edges[e]; ok {
+				continue
+			}
+			edges[e] = struct{}{}
+			writeDotEdge(w, g, e)
+		}
+	}
+	fmt.Fprintln(w, "}")
+	return w.Flush()
+}
+
+// PrintEntryPoints はエントリポイントから起点ノードに至る経路の一例をdot形式で出力します。
+func (p *dotPrinter) PrintEntryPoints(r EntryPoints) error {
+	g, eps := r.Graph, r.EntryPoints
+	paths := make([]graph.Path, 0, len(eps))
+	for _, ep := range eps {
+		paths = append(paths, ep.Path)
+	}
+	return p.PrintPaths(Paths{Graph: g, Paths: paths})
+}
+
+// PrintImpact は影響を受けるエントリポイントから変更された関数に至る経路をdot形式で出力します。
+func (p *dotPrinter) PrintImpact(r Impact) error {
+	g, impact := r.Graph, r.Impact
+	paths := make([]graph.Path, 0, len(impact.EntryPoints))
+	for _, ep := range impact.EntryPoints {
+		paths = append(paths, ep.Path)
+	}
+	return p.PrintPaths(Paths{Graph: g, Paths: paths})
+}
+
+// PrintCycles は循環ごとにメンバーをクラスタにまとめ、循環を構成する呼び出しを呼び出し箇所のラベル付きでdot形式で出力します。
+func (p *dotPrinter) PrintCycles(r Cycles) error {
+	g, cycles := r.Graph, r.Cycles
+	w := bufio.NewWriter(os.Stdout)
+	fmt.Fprintln(w, "digraph callgraph {")
+	for _, c := range cycles {
+		fmt.Fprintf(w, "  subgraph cluster_%d {\n", c.ID)
+		fmt.Fprintf(w, "    label=%s;\n", strconv.Quote(fmt.Sprintf("cycle #%d", c.ID)))
+		for _, id := range c.Members {
+			n, _ := g.Node(id)
+			fmt.Fprint(w, "  ")
+			writeDotNode(w, g, n)
+		}
+		fmt.Fprintln(w, "  }")
+	}
+	for _, c := range cycles {
+		for _, e := range c.Edges {
+			writeDotEdge(w, g, e)
+		}
+	}
+	fmt.Fprintln(w, "}")
+	return w.Flush()
+}
+
+// writeDotNode はノードを属性付きで1行出力します。
+func writeDotNode(w io.Writer, g *graph.Graph, n *graph.Node) {
+	var attrs []string
+	switch {
+	case n.ID == g.Root:
+		attrs = append(attrs, "style=bold")
+	case n.Main:
+		attrs = append(attrs, "shape=box")
+	case n.Vendor != "":
+		attrs = append(attrs, "style=dashed")
+	case n.External != "":
+		attrs = append(attrs, "color=gray")
+	}
+	if n.Cycle > 0 {
+		attrs = append(attrs, "peripheries=2")
+	}
+	if n.Truncated != "" {
+		attrs = append(attrs, "color=red")
+	}
+	attr := ""
+	if len(attrs) > 0 {
+		attr = " [" + strings.Join(attrs, ", ") + "]"
+	}
+	fmt.Fprintf(w, "  %s%s;\n", strconv.Quote(n.ID), attr)
+}
+
+// writeDotEdge はエッジを呼び出し箇所のファイル名と行番号のラベル付きで1行出力します。
+func writeDotEdge(w io.Writer, g *graph.Graph, e graph.Edge) {
+	var labels []string
+	for _, s := range g.Sites(e.Caller, e.Callee) {
+		labels = append(labels, fmt.Sprintf("%s:%d", filepath.Base(s.File), s.Line))
+	}
+	attr := ""
+	if len(labels) > 0 {
+		attr = " [label=" + strconv.Quote(strings.Join(labels, "\n")) + "]"
+	}
+	fmt.Fprintf(w, "  %s -> %s%s;\n", strconv.Quote(e.Caller), strconv.Quote(e.Callee), attr)
+}
@@ -6,23 +6,33 @@ import (
 	"fmt"
 	"os"
 
-	"github.com/meian/rev-callgraph/internal/symbol"
+	"github.com/meian/rev-callgraph/internal/graph"
 )
 
 // jsonPrinter はJSON形式でコールグラフを出力するプリンタです。
 type jsonPrinter struct {
-	style string // nested or edges
+	style    string // nested or edges
+	maxDepth int    // nested の展開深さ
 }
 
 func init() {
-	printers["json"] = func(style string) Printer {
-		return &jsonPrinter{style: style}
+	printers["json"] = func(style string, maxDepth int) Printer {
+		return &jsonPrinter{style: style, maxDepth: maxDepth}
 	}
+	p := &jsonPrinter{}
+	registerReport("json", p.PrintPaths)
+	registerReport("json", p.PrintEntryPoints)
+	registerReport("json", p.PrintImpact)
+	registerReport("json", p.PrintTests)
+	registerReport("json", p.PrintCycles)
+	registerReport("json", p.PrintDeadCode)
+	registerReport("json", p.PrintNodeStats)
 }
 
 // Print はコールグラフをJSON形式で出力します。
-func (p *jsonPrinter) Print(root *symbol.CallNode) error {
-	if root == nil {
+// nested は起点ノードから経路ごとに展開したツリーを、edges はグラフのノードとエッジをそのまま出力します。
+func (p *jsonPrinter) Print(g *graph.Graph) error {
+	if g == nil {
 		return nil
 	}
 	var (
@@ -31,55 +41,279 @@ func (p *jsonPrinter) Print(root *symbol.CallNode) error {
 	)
 	switch p.style {
 	case "edges":
-		edges := buildEdges(root)
-		data, err = json.MarshalIndent(edges, "", "  ")
+		data, err = json.MarshalIndent(edgesGraph(g), "", "  ")
 	default:
-		data, err = json.MarshalIndent(root, "", "  ")
+		data, err = json.MarshalIndent(g.Tree(p.maxDepth), "", "  ")
 	}
 	if err != nil {
 		return fmt.Errorf("JSONエンコード失敗: %w", err)
 	}
-	_, err = os.Stdout.Write(data)
-	if err == nil {
-		os.Stdout.Write([]byte("\n"))
+	return writeJSON(data)
+}
+
+// jsonEdge は呼び出し箇所の数と呼び出し箇所付きのエッジを表します。
+type jsonEdge struct {
+	graph.Edge
+	Count int      `json:"count,omitempty"`
+	Sites []string `json:"sites,omitempty"`
+}
+
+// jsonGraph は edges スタイルで出力するグラフを表します。
+type jsonGraph struct {
+	*graph.Graph
+	Edges []jsonEdge `json:"edges"`
+}
+
+// edgesGraph はグラフのエッジに呼び出し箇所の数と呼び出し箇所を加えた出力用のグラフを返します。
+func edgesGraph(g *graph.Graph) jsonGraph {
+	jg := jsonGraph{Graph: g, Edges: make([]jsonEdge, 0, len(g.Edges))}
+	for _, e := range g.Edges {
+		sites := siteList(g, e.Caller, e.Callee)
+		jg.Edges = append(jg.Edges, jsonEdge{Edge: e, Count: len(sites), Sites: sites})
 	}
-	return err
+	return jg
 }
 
-// edgesJSON はedges形式の出力構造体です
-type edgesJSON struct {
-	Root  string              `json:"root"`
-	Nodes []string            `json:"nodes"`
-	Edges []map[string]string `json:"edges"`
+// jsonHop は経路上の1つの呼び出しを表します。
+type jsonHop struct {
+	Caller string   `json:"caller"`
+	Callee string   `json:"callee"`
+	Sites  []string `json:"sites,omitempty"`
 }
 
-// buildEdges はCallNodeツリーからedges形式の構造体を生成します
-func buildEdges(root *symbol.CallNode) edgesJSON {
-	nodes := make(map[string]struct{})
-	edges := make([]map[string]string, 0)
-	var walk func(n *symbol.CallNode)
-	walk = func(n *symbol.CallNode) {
-		if n == nil {
-			return
+// jsonPath は経路を表します。
+type jsonPath struct {
+	Depth int           `json:"depth"`
+	Nodes []*graph.Node `json:"nodes"`
+	Hops  []jsonHop     `json:"hops"`
+}
+
+// PrintPaths は経路ごとに深さ、ノード、呼び出し箇所付きの呼び出しをJSON形式で出力します。
+func (p *jsonPrinter) PrintPaths(r Paths) error {
+	g, paths := r.Graph, r.Paths
+	out := struct {
+		Root  string     `json:"root"`
+		Paths []jsonPath `json:"paths"`
+	}{Root: g.Root, Paths: []jsonPath{}}
+	for _, path := range paths {
+		jp := jsonPath{Depth: path.Depth(), Hops: []jsonHop{}}
+		for j, id := range path {
+			n, _ := g.Node(id)
+			jp.Nodes = append(jp.Nodes, n)
+			if j > 0 {
+				jp.Hops = append(jp.Hops, jsonHop{Caller: path[j-1], Callee: id, Sites: siteList(g, path[j-1], id)})
+			}
 		}
-		nodes[n.Name] = struct{}{}
-		for _, c := range n.Callers {
-			edges = append(edges, map[string]string{
-				"caller": c.Name,
-				"callee": n.Name,
-			})
-			walk(c)
+		out.Paths = append(out.Paths, jp)
+	}
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return fmt.Errorf("JSONエンコード失敗: %w", err)
+	}
+	return writeJSON(data)
+}
+
+// jsonEntryPoint はエントリポイントを表します。
+type jsonEntryPoint struct {
+	*graph.Node
+	EntryKind graph.EntryKind `json:"entry_kind"`
+	Dir       string          `json:"dir"`
+	Path      graph.Path      `json:"path"`
+}
+
+// PrintEntryPoints はエントリポイントをノードの情報に種別、パッケージディレクトリ、経路の一例を加えてJSON形式で出力します。
+func (p *jsonPrinter) PrintEntryPoints(r EntryPoints) error {
+	g, eps := r.Graph, r.EntryPoints
+	out := struct {
+		Root        string           `json:"root"`
+		EntryPoints []jsonEntryPoint `json:"entrypoints"`
+	}{Root: g.Root, EntryPoints: []jsonEntryPoint{}}
+	for _, ep := range eps {
+		out.EntryPoints = append(out.EntryPoints, jsonEntryPoint{Node: ep.Node, EntryKind: ep.Kind, Dir: ep.Dir, Path: ep.Path})
+	}
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return fmt.Errorf("JSONエンコード失敗: %w", err)
+	}
+	return writeJSON(data)
+}
+
+// jsonImpactEntry は変更された関数に到達するエントリポイントを表します。
+type jsonImpactEntry struct {
+	jsonEntryPoint
+	Changed string `json:"changed"`
+}
+
+// PrintImpact は変更された関数、エントリポイント (到達する変更された関数と経路の一例を含む)、
+// パッケージ、main パッケージをJSON形式で出力します。
+func (p *jsonPrinter) PrintImpact(r Impact) error {
+	impact := r.Impact
+	out := struct {
+		Changed     []*graph.Node     `json:"changed"`
+		EntryPoints []jsonImpactEntry `json:"entrypoints"`
+		Packages    []string          `json:"packages"`
+		Binaries    []jsonBinary      `json:"binaries"`
+	}{Changed: []*graph.Node{}, EntryPoints: []jsonImpactEntry{}, Packages: []string{}, Binaries: []jsonBinary{}}
+	out.Changed = append(out.Changed, impact.Changed...)
+	for _, ep := range impact.EntryPoints {
+		out.EntryPoints = append(out.EntryPoints, jsonImpactEntry{
+			jsonEntryPoint: jsonEntryPoint{Node: ep.Node, EntryKind: ep.Kind, Dir: ep.Dir, Path: ep.Path},
+			Changed:        ep.Changed,
+		})
+	}
+	out.Packages = append(out.Packages, impact.Packages...)
+	for _, b := range impact.Binaries {
+		out.Binaries = append(out.Binaries, jsonBinary{Package: b.Package, Dir: b.Dir})
+	}
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return fmt.Errorf("JSONエンコード失敗: %w", err)
+	}
+	return writeJSON(data)
+}
+
+// jsonBinary は影響を受ける main パッケージを表します。
+type jsonBinary struct {
+	Package string `json:"package"`
+	Dir     string `json:"dir"`
+}
+
+// jsonTestPackage はパッケージディレクトリごとのテスト関数を表します。
+type jsonTestPackage struct {
+	Package    string   `json:"package"`
+	Dir        string   `json:"dir"`
+	ModuleDir  string   `json:"module_dir"`
+	Pattern    string   `json:"pattern"`
+	Tests      []string `json:"tests"`
+	Benchmarks []string `json:"benchmarks,omitempty"`
+	Command    string   `json:"command"`
+}
+
+// PrintTests はパッケージディレクトリごとのテスト関数と go test のコマンドラインをJSON形式で出力します。
+func (p *jsonPrinter) PrintTests(pkgs Tests) error {
+	out := struct {
+		Packages []jsonTestPackage `json:"packages"`
+	}{Packages: []jsonTestPackage{}}
+	for _, pkg := range pkgs {
+		tests := pkg.Tests
+		if tests == nil {
+			tests = []string{}
 		}
+		out.Packages = append(out.Packages, jsonTestPackage{
+			Package:    pkg.Package,
+			Dir:        pkg.Dir,
+			ModuleDir:  pkg.ModuleDir,
+			Pattern:    pkg.Pattern,
+			Tests:      tests,
+			Benchmarks: pkg.Benchmarks,
+			Command:    pkg.Command(),
+		})
 	}
-	walk(root)
-	// ノード名リスト化
-	ns := make([]string, 0, len(nodes))
-	for k := range nodes {
-		ns = append(ns, k)
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return fmt.Errorf("JSONエンコード失敗: %w", err)
+	}
+	return writeJSON(data)
+}
+
+// jsonCycle は循環を表します。
+type jsonCycle struct {
+	ID      int           `json:"id"`
+	Members []*graph.Node `json:"members"`
+	Calls   []jsonHop     `json:"calls"`
+}
+
+// PrintCycles は循環ごとにメンバーのノードと、循環を構成する呼び出しを呼び出し箇所付きでJSON形式で出力します。
+func (p *jsonPrinter) PrintCycles(r Cycles) error {
+	g, cycles := r.Graph, r.Cycles
+	out := struct {
+		Root   string      `json:"root"`
+		Cycles []jsonCycle `json:"cycles"`
+	}{Root: g.Root, Cycles: []jsonCycle{}}
+	for _, c := range cycles {
+		jc := jsonCycle{ID: c.ID, Members: []*graph.Node{}, Calls: []jsonHop{}}
+		for _, id := range c.Members {
+			n, _ := g.Node(id)
+			jc.Members = append(jc.Members, n)
+		}
+		for _, e := range c.Edges {
+			jc.Calls = append(jc.Calls, jsonHop{Caller: e.Caller, Callee: e.Callee, Sites: siteList(g, e.Caller, e.Callee)})
+		}
+		out.Cycles = append(out.Cycles, jc)
 	}
-	return edgesJSON{
-		Root:  root.Name,
-		Nodes: ns,
-		Edges: edges,
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return fmt.Errorf("JSONエンコード失敗: %w", err)
+	}
+	return writeJSON(data)
+}
+
+// jsonDeadPackage はパッケージ内の到達できない関数を表します。
+type jsonDeadPackage struct {
+	Package   string        `json:"package"`
+	Dir       string        `json:"dir"`
+	Functions []*graph.Node `json:"functions"`
+}
+
+// jsonDeadModule はモジュール内の到達できない関数を表します。
+type jsonDeadModule struct {
+	Module   string            `json:"module"`
+	Packages []jsonDeadPackage `json:"packages"`
+}
+
+// PrintDeadCode はモジュール、パッケージごとに到達できない関数のノードをJSON形式で出力します。
+func (p *jsonPrinter) PrintDeadCode(mods DeadCode) error {
+	out := struct {
+		Modules []jsonDeadModule `json:"modules"`
+	}{Modules: []jsonDeadModule{}}
+	for _, m := range mods {
+		jm := jsonDeadModule{Module: m.Module}
+		for _, pkg := range m.Packages {
+			jm.Packages = append(jm.Packages, jsonDeadPackage{Package: pkg.Package, Dir: pkg.Dir, Functions: pkg.Functions})
+		}
+		out.Modules = append(out.Modules, jm)
 	}
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return fmt.Errorf("JSONエンコード失敗: %w", err)
+	}
+	return writeJSON(data)
+}
+
+// jsonNodeStats はノードとその呼び出し元の統計を表します。
+type jsonNodeStats struct {
+	*graph.Node
+	DirectCallers     int  `json:"direct_callers"`
+	TransitiveCallers int  `json:"transitive_callers"`
+	EntryPoints       int  `json:"entrypoints"`
+	Depth             *int `json:"depth,omitempty"`
+}
+
+// PrintNodeStats はノードごとの統計をJSON形式で出力します。
+// 起点ノードのないグラフの場合は depth を省略します。
+func (p *jsonPrinter) PrintNodeStats(stats NodeStats) error {
+	out := struct {
+		Nodes []jsonNodeStats `json:"nodes"`
+	}{Nodes: []jsonNodeStats{}}
+	for _, s := range stats {
+		js := jsonNodeStats{Node: s.Node, DirectCallers: s.DirectCallers, TransitiveCallers: s.TransitiveCallers, EntryPoints: s.EntryPoints}
+		if s.Depth >= 0 {
+			js.Depth = &s.Depth
+		}
+		out.Nodes = append(out.Nodes, js)
+	}
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return fmt.Errorf("JSONエンコード失敗: %w", err)
+	}
+	return writeJSON(data)
+}
+
+// writeJSON はエンコード済みのJSONを改行付きで標準出力に書き込みます。
+func writeJSON(data []byte) error {
+	_, err := os.Stdout.Write(data)
+	if err == nil {
+		os.Stdout.Write([]byte("\n"))
+	}
+	return err
 }
@@ -2,37 +2,109 @@ package format
 
 import (
 	"fmt"
+	"reflect"
+	"strings"
 
-	"github.com/meian/rev-callgraph/internal/symbol"
+	"github.com/meian/rev-callgraph/internal/graph"
 )
 
 // Printer はコールグラフ出力の共通インターフェイスです。
 type Printer interface {
 	// Print はコールグラフを出力します。
-	Print(root *symbol.CallNode) error
+	// グラフは循環を含むことがあります。
+	Print(g *graph.Graph) error
 }
 
-type printerGen func(jsonStyle string) Printer
+// Paths はグラフ上の経路の出力内容です。
+// 各経路は呼び出し元から起点ノードに向かって並びます。
+type Paths struct {
+	Graph *graph.Graph
+	Paths []graph.Path
+}
+
+// EntryPoints はグラフの起点ノードに到達するエントリポイントの出力内容です。
+type EntryPoints struct {
+	Graph       *graph.Graph
+	EntryPoints []graph.EntryPoint
+}
+
+// Impact は変更された関数から構築したグラフと、その影響範囲の出力内容です。
+type Impact struct {
+	Graph  *graph.Graph
+	Impact *graph.Impact
+}
+
+// Tests はパッケージディレクトリごとのテスト関数の出力内容です。
+type Tests []graph.TestPackage
+
+// Cycles はグラフの循環ごとのメンバーと、循環を構成する呼び出しの出力内容です。
+type Cycles struct {
+	Graph  *graph.Graph
+	Cycles []graph.Cycle
+}
+
+// DeadCode はモジュール、パッケージごとの到達できない関数の出力内容です。
+type DeadCode []graph.DeadModule
+
+// NodeStats はノードごとの直接・推移的な呼び出し元の数、到達するエントリポイントの数、深さの出力内容です。
+type NodeStats []graph.NodeStats
+
+// ReportPrinter は種類 R の結果 (Paths, EntryPoints など) を出力するプリンタです。
+type ReportPrinter[R any] struct {
+	print func(R) error
+}
+
+// Print は結果を出力します。
+func (p *ReportPrinter[R]) Print(r R) error {
+	return p.print(r)
+}
+
+// reports は結果の種類と出力形式ごとに、結果を出力する関数 (func(R) error) を保持します。
+// 各形式のプリンタは init で registerReport により対応する結果の種類を登録します。
+var reports = map[reflect.Type]map[string]any{}
+
+// registerReport は format の形式で種類 R の結果を出力する関数を登録します。
+func registerReport[R any](format string, print func(R) error) {
+	t := reflect.TypeFor[R]()
+	if reports[t] == nil {
+		reports[t] = map[string]any{}
+	}
+	reports[t][format] = print
+}
+
+// NewReportPrinter はformatに応じた種類 R の結果のプリンタを返します。
+// format が R の出力に対応しない場合はエラーを返します。
+func NewReportPrinter[R any](format string) (*ReportPrinter[R], error) {
+	if _, ok := printers[format]; !ok {
+		return nil, fmt.Errorf("unsupported format: %s", format)
+	}
+	t := reflect.TypeFor[R]()
+	print, ok := reports[t][format].(func(R) error)
+	if !ok {
+		return nil, fmt.Errorf("unsupported format for %s: %s", strings.ToLower(t.Name()), format)
+	}
+	return &ReportPrinter[R]{print: print}, nil
+}
+
+type printerGen func(jsonStyle string, maxDepth int) Printer
 
 var printers = map[string]printerGen{}
 
 // NewPrinter はformatに応じたPrinterを返します。
-func NewPrinter(format, jsonStyle string) (Printer, error) {
+// maxDepth>0 の場合、ツリーとして展開する出力は深さ maxDepth で打ち切ります。
+func NewPrinter(format, jsonStyle string, maxDepth int) (Printer, error) {
 	gen, ok := printers[format]
 	if !ok {
 		return nil, fmt.Errorf("unsupported format: %s", format)
 	}
-	return gen(jsonStyle), nil
+	return gen(jsonStyle, maxDepth), nil
 }
 
-// type jsonPrinter struct{}
-// type dotPrinter struct{}
-
-// func (p *jsonPrinter) Print(n *symbol.CallNode) error {
-// 	fmt.Println("[json出力は未実装]")
-// 	return nil
-// }
-// func (p *dotPrinter) Print(n *symbol.CallNode) error {
-// 	fmt.Println("[dot出力は未実装]")
-// 	return nil
-// }
+// siteList は caller から callee への呼び出し箇所を文字列のスライスで返します。
+func siteList(g *graph.Graph, caller, callee string) []string {
+	var sites []string
+	for _, s := range g.Sites(caller, callee) {
+		sites = append(sites, s.String())
+	}
+	return sites
+}
@@ -1,46 +1,203 @@
 package format
 
 import (
+	"bufio"
 	"fmt"
+	"os"
+	"strconv"
 	"strings"
+	"text/tabwriter"
 
-	"github.com/meian/rev-callgraph/internal/symbol"
+	"github.com/meian/rev-callgraph/internal/graph"
 )
 
 // treePrinter はツリー形式でコールグラフを出力するプリンタです。
-type treePrinter struct{}
+type treePrinter struct {
+	maxDepth int
+}
 
 func init() {
-	printers["tree"] = func(string) Printer {
-		return &treePrinter{}
+	printers["tree"] = func(_ string, maxDepth int) Printer {
+		return &treePrinter{maxDepth: maxDepth}
 	}
+	p := &treePrinter{}
+	registerReport("tree", p.PrintPaths)
+	registerReport("tree", p.PrintEntryPoints)
+	registerReport("tree", p.PrintImpact)
+	registerReport("tree", p.PrintTests)
+	registerReport("tree", p.PrintCycles)
+	registerReport("tree", p.PrintDeadCode)
+	registerReport("tree", p.PrintNodeStats)
 }
 
 // Print はツリー形式でコールグラフを出力します。
-func (p *treePrinter) Print(n *symbol.CallNode) error {
-	if n == nil {
+// グラフは起点ノードから経路ごとに展開し、経路上で循環したノードは (cycled) を付けて打ち切ります。
+// 親ノードとの間の呼び出し箇所が複数ある場合は (xN) を付けます。
+func (p *treePrinter) Print(g *graph.Graph) error {
+	if g == nil {
 		return nil
 	}
-	printTree(n, 0)
-	return nil
+	w := bufio.NewWriter(os.Stdout)
+	var ids []string
+	g.Expand(p.maxDepth, func(n *graph.Node, depth int, cycled bool) {
+		ids = append(ids[:depth], n.ID)
+		line := treeLine(n, depth*2, cycled)
+		if depth > 0 {
+			caller, callee := n.ID, ids[depth-1]
+			if g.Forward {
+				caller, callee = callee, caller
+			}
+			if count := len(g.Sites(caller, callee)); count > 1 {
+				line += fmt.Sprintf(" (x%d)", count)
+			}
+		}
+		fmt.Fprintln(w, line)
+	})
+	return w.Flush()
+}
+
+// PrintPaths は経路ごとに深さと、各呼び出しの呼び出し箇所を付けてノードを1行ずつ出力します。
+func (p *treePrinter) PrintPaths(r Paths) error {
+	g, paths := r.Graph, r.Paths
+	w := bufio.NewWriter(os.Stdout)
+	for i, path := range paths {
+		fmt.Fprintf(w, "#%d depth %d\n", i+1, path.Depth())
+		for j, id := range path {
+			n, _ := g.Node(id)
+			line := treeLine(n, (j+1)*2, false)
+			if j > 0 {
+				if sites := siteList(g, path[j-1], id); len(sites) > 0 {
+					line += " (called at " + strings.Join(sites, ", ") + ")"
+				}
+			}
+			fmt.Fprintln(w, line)
+		}
+	}
+	return w.Flush()
+}
+
+// PrintEntryPoints はエントリポイントごとに種別、モジュール、パッケージディレクトリと、
+// 起点ノードに至る経路の一例を出力します。
+func (p *treePrinter) PrintEntryPoints(r EntryPoints) error {
+	eps := r.EntryPoints
+	w := bufio.NewWriter(os.Stdout)
+	for _, ep := range eps {
+		fmt.Fprintf(w, "[%s] %s\n", ep.Kind, treeLine(ep.Node, 0, false))
+		fmt.Fprintf(w, "  module: %s\n", ep.Node.Module)
+		fmt.Fprintf(w, "  dir: %s\n", ep.Dir)
+		fmt.Fprintf(w, "  path: %s\n", strings.Join(ep.Path, " -> "))
+	}
+	return w.Flush()
 }
 
-// printTree はCallNodeを再帰的にツリー表示します。
-func printTree(n *symbol.CallNode, indent int) {
-	if n == nil {
-		return
+// PrintImpact は変更された関数、影響を受けるエントリポイントと変更された関数に至る経路、
+// パッケージ、main パッケージを順に出力します。
+func (p *treePrinter) PrintImpact(r Impact) error {
+	impact := r.Impact
+	w := bufio.NewWriter(os.Stdout)
+	fmt.Fprintln(w, "changed:")
+	for _, n := range impact.Changed {
+		fmt.Fprintln(w, treeLine(n, 2, false))
 	}
+	fmt.Fprintln(w, "entry points:")
+	for _, ep := range impact.EntryPoints {
+		fmt.Fprintf(w, "  [%s] %s (from %s)\n", ep.Kind, treeLine(ep.Node, 0, false), ep.Changed)
+		fmt.Fprintf(w, "    path: %s\n", strings.Join(ep.Path, " -> "))
+	}
+	fmt.Fprintln(w, "packages:")
+	for _, pkg := range impact.Packages {
+		fmt.Fprintf(w, "  %s\n", pkg)
+	}
+	fmt.Fprintln(w, "binaries:")
+	for _, b := range impact.Binaries {
+		fmt.Fprintf(w, "  %s (%s)\n", b.Package, b.Dir)
+	}
+	return w.Flush()
+}
+
+// PrintTests はパッケージディレクトリごとに、テスト関数のみを実行する go test のコマンドラインを出力します。
+func (p *treePrinter) PrintTests(pkgs Tests) error {
+	w := bufio.NewWriter(os.Stdout)
+	for _, pkg := range pkgs {
+		fmt.Fprintln(w, pkg.Command())
+	}
+	return w.Flush()
+}
+
+// PrintCycles は循環ごとにメンバーと、循環を構成する呼び出しを呼び出し箇所付きで出力します。
+func (p *treePrinter) PrintCycles(r Cycles) error {
+	g, cycles := r.Graph, r.Cycles
+	w := bufio.NewWriter(os.Stdout)
+	for _, c := range cycles {
+		fmt.Fprintf(w, "cycle #%d (members: %d)\n", c.ID, len(c.Members))
+		for _, id := range c.Members {
+			n, _ := g.Node(id)
+			fmt.Fprintln(w, treeLine(n, 2, false))
+		}
+		fmt.Fprintln(w, "  calls:")
+		for _, e := range c.Edges {
+			line := "    " + e.Caller + " -> " + e.Callee
+			if sites := siteList(g, e.Caller, e.Callee); len(sites) > 0 {
+				line += " (called at " + strings.Join(sites, ", ") + ")"
+			}
+			fmt.Fprintln(w, line)
+		}
+	}
+	return w.Flush()
+}
+
+// PrintDeadCode はモジュール、パッケージごとに到達できない関数を定義位置付きで出力します。
+func (p *treePrinter) PrintDeadCode(mods DeadCode) error {
+	w := bufio.NewWriter(os.Stdout)
+	for _, m := range mods {
+		fmt.Fprintf(w, "module %s\n", m.Module)
+		for _, pkg := range m.Packages {
+			fmt.Fprintf(w, "  package %s (%s)\n", pkg.Package, pkg.Dir)
+			for _, n := range pkg.Functions {
+				fmt.Fprintf(w, "%s (%s)\n", treeLine(n, 4, false), n.Pos)
+			}
+		}
+	}
+	return w.Flush()
+}
+
+// PrintNodeStats はノードごとの統計を表形式で出力します。
+// 起点ノードのないグラフの深さは "-" と出力します。
+func (p *treePrinter) PrintNodeStats(stats NodeStats) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "DEPTH\tCALLERS\tTRANSITIVE\tENTRYPOINTS\tFUNCTION")
+	for _, s := range stats {
+		depth := "-"
+		if s.Depth >= 0 {
+			depth = strconv.Itoa(s.Depth)
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%s\n", depth, s.DirectCallers, s.TransitiveCallers, s.EntryPoints, treeLine(s.Node, 0, false))
+	}
+	return w.Flush()
+}
+
+// treeLine はノードをインデント付きの1行に整形します。
+func treeLine(n *graph.Node, indent int, cycled bool) string {
 	var b strings.Builder
 	b.WriteString(strings.Repeat(" ", indent))
 	b.WriteString(n.Name)
 	if n.Main {
 		b.WriteString(" [main]")
 	}
-	if n.Cycled {
+	if n.Vendor != "" {
+		b.WriteString(" [vendored by " + n.Vendor + "]")
+	}
+	if n.External != "" {
+		b.WriteString(" [" + n.External + "]")
+	}
+	if n.Cycle > 0 {
+		b.WriteString(fmt.Sprintf(" [cycle %d]", n.Cycle))
+	}
+	if cycled {
 		b.WriteString(" (cycled)")
 	}
-	fmt.Println(b.String())
-	for _, c := range n.Callers {
-		printTree(c, indent+2)
+	if n.Truncated != "" {
+		b.WriteString(" (truncated: " + string(n.Truncated) + ")")
 	}
+	return b.String()
 }
@@ -0,0 +1,215 @@
+// Package git はローカルの git コマンドを呼び出し、コミットと変更ファイルを取得します
+package git
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Head は dir を含むリポジトリの HEAD のコミットハッシュを返します
+// dir が git リポジトリでない場合や git コマンドが見つからない場合はエラーを返します
+func Head(ctx context.Context, dir string) (string, error) {
+	out, err := run(ctx, dir, "rev-parse", "--verify", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// IsAncestor は commit が head の祖先 (head 自身を含む) かどうかを判定します
+// commit がリポジトリに存在しない場合は false を返します
+func IsAncestor(ctx context.Context, dir, commit, head string) (bool, error) {
+	if _, err := run(ctx, dir, "cat-file", "-e", commit+"^{commit}"); err != nil {
+		var ee *exec.ExitError
+		if errors.As(err, &ee) {
+			return false, nil
+		}
+		return false, err
+	}
+	_, err := run(ctx, dir, "merge-base", "--is-ancestor", commit, head)
+	var ee *exec.ExitError
+	if errors.As(err, &ee) && ee.ExitCode() == 1 {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// ChangedFiles は commit から作業ツリーまでに変更・追加・削除された dir 以下のファイルと、
+// 追跡されていないファイル (.gitignore の対象を除く) の絶対パスを辞書順で返します
+// 名前を変更されたファイルは変更前のパスの削除と変更後のパスの追加として両方を返します
+func ChangedFiles(ctx context.Context, dir, commit string) ([]string, error) {
+	diff, err := run(ctx, dir, "diff", "--name-only", "--no-renames", "--relative", "-z", commit, "--")
+	if err != nil {
+		return nil, err
+	}
+	untracked, err := run(ctx, dir, "ls-files", "--others", "--exclude-standard", "-z")
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, out := range [][]byte{diff, untracked} {
+		for _, name := range bytes.Split(out, []byte{0}) {
+			if len(name) == 0 {
+				continue
+			}
+			files = append(files, filepath.Join(dir, filepath.FromSlash(string(name))))
+		}
+	}
+	slices.Sort(files)
+	return slices.Compact(files), nil
+}
+
+// run は dir をカレントディレクトリとして git コマンドを実行し、標準出力を返します
+func run(ctx context.Context, dir string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		// -c による設定の上書きを除いたサブコマンド名でエラーを報告する
+		sub := args[0]
+		for i := 0; i+2 < len(args) && args[i] == "-c"; i += 2 {
+			sub = args[i+2]
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("git %s: %w: %s", sub, err, msg)
+		}
+		return nil, fmt.Errorf("git %s: %w", sub, err)
+	}
+	return out, nil
+}
+
+// LineRange は変更後のファイルの行範囲 [Start, End] (1始まり) を表します
+type LineRange struct {
+	Start int
+	End   int
+}
+
+// ChangedLines は base と HEAD のマージベースから作業ツリーまでの差分を取得し、
+// dir 以下の変更されたファイルの絶対パスごとに変更後の行範囲を返します
+// 行の削除のみの箇所は削除位置の直前の行を変更された行とします
+// 追跡されていないファイル (.gitignore の対象を除く) は全ての行を変更された行とし、削除されたファイルは含みません
+func ChangedLines(ctx context.Context, dir, base string) (map[string][]LineRange, error) {
+	// diff.mnemonicPrefix や diff.noprefix の設定によらずパスを取り出せるように接頭辞を付けず、
+	// 非 ASCII のファイル名は引用符で囲まずに出力させる
+	out, err := run(ctx, dir, "-c", "core.quotePath=false", "diff", "--merge-base", base,
+		"--unified=0", "--no-color", "--no-ext-diff", "--no-prefix", "--relative", "--")
+	if err != nil {
+		return nil, err
+	}
+	untracked, err := run(ctx, dir, "ls-files", "--others", "--exclude-standard", "-z")
+	if err != nil {
+		return nil, err
+	}
+	result := parseDiff(dir, out)
+	for _, name := range bytes.Split(untracked, []byte{0}) {
+		if len(name) == 0 {
+			continue
+		}
+		file := filepath.Join(dir, filepath.FromSlash(string(name)))
+		result[file] = []LineRange{{Start: 1, End: math.MaxInt}}
+	}
+	return result, nil
+}
+
+// parseDiff は --unified=0 の差分からファイルごとの変更後の行範囲を取り出します
+// ハンクの行数を数えて内容の行を読み飛ばし、"+++ " で始まる追加行をファイル名行と誤認しないようにします
+func parseDiff(dir string, out []byte) map[string][]LineRange {
+	result := make(map[string][]LineRange)
+	var (
+		file string
+		// oldLeft, newLeft は読み進めているハンクの残りの削除行と追加行の数
+		oldLeft, newLeft int
+		// header は直前の行が "--- " で始まる変更前のファイル名行かどうか
+		header bool
+	)
+	for _, line := range strings.Split(string(out), "\n") {
+		if oldLeft > 0 || newLeft > 0 {
+			switch {
+			case strings.HasPrefix(line, "-"):
+				oldLeft--
+			case strings.HasPrefix(line, "+"):
+				newLeft--
+			case strings.HasPrefix(line, " "):
+				oldLeft--
+				newLeft--
+			}
+			continue
+		}
+		prev := header
+		header = strings.HasPrefix(line, "--- ")
+		switch {
+		case prev && strings.HasPrefix(line, "+++ "):
+			name := diffPath(strings.TrimPrefix(line, "+++ "))
+			if name == "/dev/null" {
+				file = ""
+				continue
+			}
+			file = filepath.Join(dir, filepath.FromSlash(name))
+		case strings.HasPrefix(line, "@@ "):
+			// @@ -a,b +c,d @@
+			fields := strings.Fields(line)
+			if len(fields) < 3 {
+				continue
+			}
+			_, oldCount, ok := parseHunkRange(strings.TrimPrefix(fields[1], "-"))
+			if !ok {
+				continue
+			}
+			start, count, ok := parseHunkRange(strings.TrimPrefix(fields[2], "+"))
+			if !ok {
+				continue
+			}
+			oldLeft, newLeft = oldCount, count
+			if file == "" {
+				continue
+			}
+			end := start + count - 1
+			if count == 0 {
+				end = start
+			}
+			if start < 1 {
+				start = 1
+			}
+			result[file] = append(result[file], LineRange{Start: start, End: max(start, end)})
+		}
+	}
+	return result
+}
+
+// diffPath は --no-prefix の差分のファイル名行からパスを取り出します
+// 空白を含むパスの末尾のタブを除き、制御文字や引用符を含むために引用符で囲まれたパスは展開します
+func diffPath(name string) string {
+	name = strings.TrimSuffix(name, "\t")
+	if strings.HasPrefix(name, `"`) {
+		if unquoted, err := strconv.Unquote(name); err == nil {
+			return unquoted
+		}
+	}
+	return name
+}
+
+// parseHunkRange は "c,d" または "c" 形式の範囲を開始行と行数に分解します
+func parseHunkRange(s string) (start, count int, ok bool) {
+	startStr, countStr, hasCount := strings.Cut(s, ",")
+	start, err := strconv.Atoi(startStr)
+	if err != nil {
+		return 0, 0, false
+	}
+	count = 1
+	if hasCount {
+		if count, err = strconv.Atoi(countStr); err != nil {
+			return 0, 0, false
+		}
+	}
+	return start, count, true
+}
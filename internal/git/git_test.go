@@ -0,0 +1,122 @@
+package git_test
+
+import (
+	"context"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/meian/rev-callgraph/internal/git"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// gitRun は dir で git コマンドを実行します
+func gitRun(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+		"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com")
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, "git %v: %s", args, out)
+}
+
+func TestChangedLines(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git が見つかりません")
+	}
+	root := t.TempDir()
+	fileA := filepath.Join(root, "a.go")
+	fileB := filepath.Join(root, "b.go")
+	require.NoError(t, os.WriteFile(fileA, []byte("package p\n\nfunc A() {\n\tx := 1\n\t_ = x\n}\n"), 0644))
+	require.NoError(t, os.WriteFile(fileB, []byte("package p\n"), 0644))
+	gitRun(t, root, "init", "-q", "-b", "main")
+	gitRun(t, root, "add", ".")
+	gitRun(t, root, "commit", "-q", "-m", "init")
+	gitRun(t, root, "checkout", "-q", "-b", "topic")
+
+	// コミットされた変更 (行の変更) と作業ツリーの変更 (行の削除)、追跡されていないファイル、削除されたファイル
+	require.NoError(t, os.WriteFile(fileA, []byte("package p\n\nfunc A() {\n\tx := 2\n\t_ = x\n}\n"), 0644))
+	gitRun(t, root, "commit", "-q", "-am", "a")
+	require.NoError(t, os.WriteFile(fileA, []byte("package p\n\nfunc A() {\n\tx := 2\n}\n"), 0644))
+	fileC := filepath.Join(root, "c.go")
+	require.NoError(t, os.WriteFile(fileC, []byte("package p\n"), 0644))
+	require.NoError(t, os.Remove(fileB))
+
+	got, err := git.ChangedLines(context.Background(), root, "main")
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]git.LineRange{
+		fileA: {{Start: 4, End: 4}},
+		fileC: {{Start: 1, End: math.MaxInt}},
+	}, got)
+}
+
+func TestChangedLines_DiffConfig(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git が見つかりません")
+	}
+	root := t.TempDir()
+	// 接頭辞と同じ名前のディレクトリや非 ASCII・空白を含むファイル名
+	files := []string{
+		filepath.Join(root, "a.go"),
+		filepath.Join(root, "b", "b.go"),
+		filepath.Join(root, "w", "日本語.go"),
+		filepath.Join(root, "with space.go"),
+	}
+	for _, f := range files {
+		require.NoError(t, os.MkdirAll(filepath.Dir(f), 0755))
+		require.NoError(t, os.WriteFile(f, []byte("package p\n"), 0644))
+	}
+	gitRun(t, root, "init", "-q", "-b", "main")
+	gitRun(t, root, "add", ".")
+	gitRun(t, root, "commit", "-q", "-m", "init")
+
+	// ユーザーの設定で差分の接頭辞やパスの引用が変わっても変更されたファイルを特定する
+	for _, config := range [][]string{
+		{"diff.mnemonicPrefix", "true"},
+		{"diff.noprefix", "true"},
+		{"diff.srcPrefix", "x/"},
+		{"core.quotePath", "true"},
+	} {
+		t.Run(config[0], func(t *testing.T) {
+			gitRun(t, root, "config", config[0], config[1])
+			defer gitRun(t, root, "config", "--unset", config[0])
+			for _, f := range files {
+				require.NoError(t, os.WriteFile(f, []byte("package p\n\nvar x = 1\n"), 0644))
+			}
+			defer gitRun(t, root, "checkout", "-q", ".")
+
+			got, err := git.ChangedLines(context.Background(), root, "main")
+			require.NoError(t, err)
+			want := make(map[string][]git.LineRange)
+			for _, f := range files {
+				want[f] = []git.LineRange{{Start: 2, End: 3}}
+			}
+			assert.Equal(t, want, got)
+		})
+	}
+}
+
+func TestChangedLines_HeaderLikeContent(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git が見つかりません")
+	}
+	root := t.TempDir()
+	file := filepath.Join(root, "a.go")
+	require.NoError(t, os.WriteFile(file, []byte("package p\n-- old\nx\ny\nz\n"), 0644))
+	gitRun(t, root, "init", "-q", "-b", "main")
+	gitRun(t, root, "add", ".")
+	gitRun(t, root, "commit", "-q", "-m", "init")
+
+	// "-- " や "++ " で始まる行の変更は差分で "--- " や "+++ " となるが、ファイル名行とはみなさない
+	require.NoError(t, os.WriteFile(file, []byte("package p\n++ new\nx\ny\nz2\n"), 0644))
+	got, err := git.ChangedLines(context.Background(), root, "main")
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]git.LineRange{
+		file: {{Start: 2, End: 2}, {Start: 5, End: 5}},
+	}, got)
+}
@@ -0,0 +1,148 @@
+package gomod
+
+import (
+	"context"
+	"errors"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/meian/rev-callgraph/internal/cache"
+	"github.com/meian/rev-callgraph/internal/diag"
+	"github.com/meian/rev-callgraph/internal/srcfile"
+	"github.com/meian/rev-callgraph/internal/stats"
+	"github.com/meian/rev-callgraph/internal/symbol"
+)
+
+// DefKind は定義の種別を表します
+type DefKind string
+
+const (
+	// DefFunc は関数定義を表します
+	DefFunc DefKind = "func"
+	// DefMethod はメソッド定義を表します
+	DefMethod DefKind = "method"
+)
+
+// Definition はパッケージ内の関数/メソッド定義を表します
+type Definition struct {
+	// Function は定義された関数/メソッド
+	Function symbol.Function
+	// Kind は定義の種別
+	Kind DefKind
+	// PointerRecv はレシーバがポインタ型かどうか (メソッドのみ)
+	PointerRecv bool
+	// Pos は定義位置
+	Pos token.Position
+	// Doc は定義に付与されたドキュメントコメント
+	Doc string
+}
+
+// PackageDefs はパッケージ内の定義を名前 (Function.String()) で引けるようにしたものです
+type PackageDefs map[string]Definition
+
+// Lookup は f に一致する定義を返します
+func (p PackageDefs) Lookup(f symbol.Function) (Definition, bool) {
+	def, ok := p[f.String()]
+	return def, ok
+}
+
+// DefinitionIndex はパッケージディレクトリ単位で定義を索引するキャッシュです
+// 各パッケージは初回参照時に一度だけ解析され、異なるパッケージの解析は並行に行われます
+type DefinitionIndex struct {
+	mu   sync.Mutex
+	pkgs map[string]*defsEntry
+}
+
+// defsEntry は解析中または解析済みのパッケージの定義です
+// done が閉じられた後に defs と err を参照できます
+type defsEntry struct {
+	done chan struct{}
+	defs PackageDefs
+	err  error
+}
+
+// NewDefinitionIndex は空の DefinitionIndex を作成します
+func NewDefinitionIndex() *DefinitionIndex {
+	return &DefinitionIndex{pkgs: map[string]*defsEntry{}}
+}
+
+// Package は pkgPath のパッケージが配置された dir を解析し、定義一覧を返します
+// 解析結果は dir をキーにキャッシュされ、同じ dir の解析中に呼ばれた場合はその結果を待ちます
+// 解析に失敗した場合はキャッシュせず、以降の呼び出しで再び解析します
+func (idx *DefinitionIndex) Package(ctx context.Context, pkgPath, dir string) (PackageDefs, error) {
+	idx.mu.Lock()
+	e, ok := idx.pkgs[dir]
+	if !ok {
+		e = &defsEntry{done: make(chan struct{})}
+		idx.pkgs[dir] = e
+	}
+	idx.mu.Unlock()
+	if ok {
+		select {
+		case <-e.done:
+			return e.defs, e.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	// ロックの外で解析し、他のパッケージの参照を妨げない
+	e.defs, e.err = ParsePackageDefs(ctx, pkgPath, dir)
+	if e.err != nil {
+		idx.mu.Lock()
+		delete(idx.pkgs, dir)
+		idx.mu.Unlock()
+	} else {
+		stats.Add(ctx, stats.PackagesDefined, 1)
+	}
+	close(e.done)
+	return e.defs, e.err
+}
+
+// ParsePackageDefs は dir 直下の .go ファイルを解析し、トップレベルの関数/メソッド定義を収集します
+// 構文エラーのあるファイルや読めないファイルはスキップし、サイズ上限を超えるファイルは診断を出力します
+// コンテキストが中断された場合は ctx.Err() を返します
+func ParsePackageDefs(ctx context.Context, pkgPath, dir string) (PackageDefs, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	defs := PackageDefs{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".go") {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		file, err := cache.Load(ctx, path)
+		if err != nil {
+			// 中断による失敗をスキップすると定義の欠けた結果がキャッシュされるため、エラーとする
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			if errors.Is(err, srcfile.ErrTooLarge) {
+				diag.Warnf(ctx, path, "定義の解析をスキップしました: %v", err)
+			}
+			continue // 解析できないファイルはスキップ
+		}
+		for _, fn := range file.Funcs {
+			def := Definition{
+				Function: symbol.Function{PkgPath: pkgPath, TypeName: fn.Recv, Name: fn.Name},
+				Kind:     DefFunc,
+				Pos:      token.Position{Filename: path, Offset: fn.Pos.Offset, Line: fn.Pos.Line, Column: fn.Pos.Column},
+				Doc:      fn.Doc,
+			}
+			if fn.IsMethod() {
+				def.Kind = DefMethod
+				def.PointerRecv = fn.PointerRecv
+			}
+			defs[def.Function.String()] = def
+		}
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	return defs, nil
+}
@@ -0,0 +1,145 @@
+package gomod_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/meian/rev-callgraph/internal/gomod"
+	"github.com/meian/rev-callgraph/internal/stats"
+	"github.com/meian/rev-callgraph/internal/symbol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePackageDefs(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.go"), []byte(`package pkg
+
+// Target はテスト用の関数です
+func Target() {}
+
+type List[T any] struct{}
+
+func (l *List[T]) Push(v T) {}
+
+func (
+	m Map[K, V],
+) Get(k K) V { var v V; return v }
+
+type Map[K comparable, V any] map[K]V
+
+func (Value) String() string { return "" }
+
+type Value struct{}
+
+// func Commented() {}
+var s = "func InString() {"
+`), 0644))
+
+	defs, err := gomod.ParsePackageDefs(context.Background(), "example.com/pkg", dir)
+	require.NoError(t, err)
+
+	def, ok := defs.Lookup(symbol.Function{PkgPath: "example.com/pkg", Name: "Target"})
+	require.True(t, ok, "Target が見つかりません")
+	assert.Equal(t, gomod.DefFunc, def.Kind)
+	assert.Equal(t, "Target はテスト用の関数です", def.Doc)
+	assert.Equal(t, 4, def.Pos.Line)
+
+	def, ok = defs.Lookup(symbol.Function{PkgPath: "example.com/pkg", TypeName: "List", Name: "Push"})
+	require.True(t, ok, "ジェネリック型のメソッドが見つかりません")
+	assert.Equal(t, gomod.DefMethod, def.Kind)
+	assert.True(t, def.PointerRecv)
+
+	def, ok = defs.Lookup(symbol.Function{PkgPath: "example.com/pkg", TypeName: "Map", Name: "Get"})
+	require.True(t, ok, "複数行レシーバのメソッドが見つかりません")
+	assert.False(t, def.PointerRecv)
+
+	def, ok = defs.Lookup(symbol.Function{PkgPath: "example.com/pkg", TypeName: "Value", Name: "String"})
+	require.True(t, ok, "名前なしレシーバのメソッドが見つかりません")
+	assert.False(t, def.PointerRecv)
+
+	_, ok = defs.Lookup(symbol.Function{PkgPath: "example.com/pkg", Name: "Commented"})
+	assert.False(t, ok, "コメント内の定義にマッチしてはいけません")
+	_, ok = defs.Lookup(symbol.Function{PkgPath: "example.com/pkg", Name: "InString"})
+	assert.False(t, ok, "文字列リテラル内の定義にマッチしてはいけません")
+}
+
+func TestModuleMap_LookupDefinition(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "sub"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "sub", "sub.go"), []byte(`package sub
+
+func Target() {}
+`), 0644))
+	mods := gomod.NewModuleMap(map[string]gomod.Module{
+		"example.com/m": {Path: "example.com/m", Root: root},
+	})
+
+	mod, def, err := mods.LookupDefinition(context.Background(), symbol.Function{PkgPath: "example.com/m/sub", Name: "Target"})
+	require.NoError(t, err)
+	require.NotNil(t, mod)
+	assert.Equal(t, "example.com/m", mod.Path)
+	assert.Equal(t, filepath.Join(root, "sub", "sub.go"), def.Pos.Filename)
+
+	mod, def, err = mods.LookupDefinition(context.Background(), symbol.Function{PkgPath: "example.com/m/sub", Name: "Missing"})
+	require.NoError(t, err)
+	assert.Nil(t, mod)
+	assert.Nil(t, def)
+}
+
+func TestDefinitionIndex_Concurrent(t *testing.T) {
+	root := t.TempDir()
+	const pkgs = 8
+	for i := range pkgs {
+		dir := filepath.Join(root, fmt.Sprintf("p%d", i))
+		require.NoError(t, os.MkdirAll(dir, 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "p.go"), []byte(fmt.Sprintf("package p%d\n\nfunc F() {}\n", i)), 0644))
+	}
+	rec := stats.NewRecorder()
+	ctx := stats.WithRecorder(context.Background(), rec)
+	idx := gomod.NewDefinitionIndex()
+
+	// 複数のパッケージを並行に参照しても、各パッケージは一度だけ解析され同じ結果を返す
+	var wg sync.WaitGroup
+	results := make([]gomod.PackageDefs, pkgs*4)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p := i % pkgs
+			defs, err := idx.Package(ctx, fmt.Sprintf("example.com/p%d", p), filepath.Join(root, fmt.Sprintf("p%d", p)))
+			assert.NoError(t, err)
+			results[i] = defs
+		}()
+	}
+	wg.Wait()
+	for i, defs := range results {
+		_, ok := defs.Lookup(symbol.Function{PkgPath: fmt.Sprintf("example.com/p%d", i%pkgs), Name: "F"})
+		assert.True(t, ok)
+	}
+	assert.Equal(t, int64(pkgs), rec.Count(stats.PackagesDefined))
+
+	// 解析に失敗したパッケージはキャッシュしない
+	_, err := idx.Package(ctx, "example.com/missing", filepath.Join(root, "missing"))
+	assert.Error(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "missing"), 0755))
+	_, err = idx.Package(ctx, "example.com/missing", filepath.Join(root, "missing"))
+	assert.NoError(t, err)
+
+	// 中断された解析は途中までの定義をキャッシュせず、以降の呼び出しで再び解析する
+	dir := filepath.Join(root, "canceled")
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "p.go"), []byte("package canceled\n\nfunc F() {}\n"), 0644))
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = idx.Package(canceled, "example.com/canceled", dir)
+	assert.ErrorIs(t, err, context.Canceled)
+	defs, err := idx.Package(ctx, "example.com/canceled", dir)
+	require.NoError(t, err)
+	_, ok := defs.Lookup(symbol.Function{PkgPath: "example.com/canceled", Name: "F"})
+	assert.True(t, ok)
+}
@@ -0,0 +1,127 @@
+package gomod
+
+import (
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/meian/rev-callgraph/internal/graph"
+)
+
+// DepsGraph は m を起点に、ワークスペース内で require しているモジュールを辿ったグラフを返します
+// 各エッジの Caller には依存先モジュールが格納されます
+func (mm ModuleMap) DepsGraph(m Module) *graph.Graph {
+	return mm.depGraph(m, mm.requires)
+}
+
+// requires は m が require しているワークスペース内のモジュールを返します
+func (mm ModuleMap) requires(m Module) []Module {
+	var deps []Module
+	for _, req := range m.Requires {
+		if dep, ok := mm.mmap[req]; ok {
+			deps = append(deps, dep)
+		}
+	}
+	return deps
+}
+
+// RevDepsGraph は m を起点に、ワークスペース内で m を require しているモジュールを辿ったグラフを返します
+// 各エッジの Caller には参照元モジュールが格納されます
+func (mm ModuleMap) RevDepsGraph(m Module) *graph.Graph {
+	return mm.depGraph(m, mm.requiredBy)
+}
+
+// Roots は依存グラフの起点となるモジュールを返します
+// reverse=false の場合は他のワークスペース内モジュールから require されていないもの、
+// reverse=true の場合はワークスペース内のモジュールを require していないものを返します
+// require が循環しているモジュールのように該当するモジュールから辿れないモジュールが残らないように、
+// 他から辿れない強連結成分 (循環) ごとにパスが最小のモジュールも起点とします
+func (mm ModuleMap) Roots(reverse bool) []Module {
+	next, prev := mm.requires, mm.requiredBy
+	if reverse {
+		next, prev = prev, next
+	}
+	reached := make(map[string]bool)
+	var roots []Module
+	for _, p := range mm.sortedPaths() {
+		mod := mm.mmap[p]
+		if mod.IsVendored() || reached[mod.Path] {
+			continue
+		}
+		// 自身に辿り着く全てのモジュールに自身から辿れる場合 (辿り着くモジュールがない場合を含む)、
+		// mod は他から辿れない強連結成分に属する
+		down, source := reachable(mod, next), true
+		for up := range reachable(mod, prev) {
+			if !down[up] {
+				source = false
+				break
+			}
+		}
+		if !source {
+			continue
+		}
+		roots = append(roots, mod)
+		maps.Copy(reached, down)
+	}
+	return roots
+}
+
+// reachable は m から next で辿れるモジュールのパスの集合 (m を含む) を返します
+func reachable(m Module, next func(Module) []Module) map[string]bool {
+	seen := map[string]bool{m.Path: true}
+	stack := []Module{m}
+	for len(stack) > 0 {
+		cur := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for _, a := range next(cur) {
+			if !seen[a.Path] {
+				seen[a.Path] = true
+				stack = append(stack, a)
+			}
+		}
+	}
+	return seen
+}
+
+// Sorted はモジュールをパスの辞書順で返します
+func (mm ModuleMap) Sorted() []Module {
+	mods := make([]Module, 0, len(mm.paths))
+	for _, p := range mm.sortedPaths() {
+		mods = append(mods, mm.mmap[p])
+	}
+	return mods
+}
+
+// sortedPaths はモジュールパスを辞書順で返します
+func (mm ModuleMap) sortedPaths() []string {
+	paths := slices.Clone(mm.paths)
+	slices.Sort(paths)
+	return paths
+}
+
+// depGraph は next で得られる隣接モジュールを深さ優先で辿ったグラフを構築します
+// 隣接モジュールはパスの辞書順にエッジを追加し、各モジュールは一度だけ展開します
+func (mm ModuleMap) depGraph(m Module, next func(Module) []Module) *graph.Graph {
+	g := graph.New(moduleNode(m))
+	var visit func(m Module)
+	visit = func(m Module) {
+		adj := next(m)
+		slices.SortFunc(adj, func(a, b Module) int {
+			return strings.Compare(a.Path, b.Path)
+		})
+		for _, a := range adj {
+			n, added := g.AddNode(moduleNode(a))
+			g.AddEdge(n.ID, m.Path)
+			if added {
+				visit(a)
+			}
+		}
+	}
+	visit(m)
+	return g
+}
+
+// moduleNode はモジュールを表すグラフのノードを返します
+func moduleNode(m Module) *graph.Node {
+	return &graph.Node{ID: m.Path, Name: m.Path, Kind: graph.KindModule}
+}
@@ -0,0 +1,82 @@
+package gomod_test
+
+import (
+	"testing"
+
+	"github.com/meian/rev-callgraph/internal/gomod"
+	"github.com/meian/rev-callgraph/internal/graph"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModuleMap_DepsGraph(t *testing.T) {
+	mods := gomod.NewModuleMap(map[string]gomod.Module{
+		"example.com/app":  {Path: "example.com/app", Requires: []string{"example.com/lib", "example.com/ext"}},
+		"example.com/lib":  {Path: "example.com/lib", Requires: []string{"example.com/base"}},
+		"example.com/base": {Path: "example.com/base", Requires: []string{"example.com/lib"}},
+	})
+	app, _ := mods.Get("example.com/app")
+	base, _ := mods.Get("example.com/base")
+
+	// ワークスペース外の依存 (example.com/ext) は含まず、循環は Cycled で打ち切る
+	assert.Equal(t, &graph.TreeNode{
+		Name: "example.com/app",
+		Callers: []*graph.TreeNode{
+			{Name: "example.com/lib", Callers: []*graph.TreeNode{
+				{Name: "example.com/base", Callers: []*graph.TreeNode{
+					{Name: "example.com/lib", Cycled: true},
+				}},
+			}},
+		},
+	}, mods.DepsGraph(app).Tree(0))
+
+	assert.Equal(t, &graph.TreeNode{
+		Name: "example.com/base",
+		Callers: []*graph.TreeNode{
+			{Name: "example.com/lib", Callers: []*graph.TreeNode{
+				{Name: "example.com/app"},
+				{Name: "example.com/base", Cycled: true},
+			}},
+		},
+	}, mods.RevDepsGraph(base).Tree(0))
+
+	roots := mods.Roots(false)
+	assert.Len(t, roots, 1)
+	assert.Equal(t, "example.com/app", roots[0].Path)
+
+	// 逆方向では循環 (lib, base) のうちパスが最小のモジュールを起点とする
+	roots = mods.Roots(true)
+	assert.Len(t, roots, 1)
+	assert.Equal(t, "example.com/base", roots[0].Path)
+}
+
+func TestModuleMap_Roots_Cycle(t *testing.T) {
+	// 互いに require している2つのモジュールと、それと独立したモジュール
+	mods := gomod.NewModuleMap(map[string]gomod.Module{
+		"example.com/a":    {Path: "example.com/a", Requires: []string{"example.com/b"}},
+		"example.com/b":    {Path: "example.com/b", Requires: []string{"example.com/a"}},
+		"example.com/solo": {Path: "example.com/solo"},
+		"example.com/tool": {Path: "example.com/tool", Requires: []string{"example.com/util"}},
+		"example.com/util": {Path: "example.com/util"},
+	})
+	paths := func(mods []gomod.Module) []string {
+		var result []string
+		for _, m := range mods {
+			result = append(result, m.Path)
+		}
+		return result
+	}
+
+	// 他から辿れない循環はパスが最小のモジュールを起点とし、全てのモジュールがいずれかの起点から辿れる
+	assert.Equal(t, []string{"example.com/a", "example.com/solo", "example.com/tool"}, paths(mods.Roots(false)))
+	assert.Equal(t, []string{"example.com/a", "example.com/solo", "example.com/util"}, paths(mods.Roots(true)))
+
+	a, _ := mods.Get("example.com/a")
+	assert.Equal(t, &graph.TreeNode{
+		Name: "example.com/a",
+		Callers: []*graph.TreeNode{
+			{Name: "example.com/b", Callers: []*graph.TreeNode{
+				{Name: "example.com/a", Cycled: true},
+			}},
+		},
+	}, mods.DepsGraph(a).Tree(0))
+}
@@ -3,13 +3,12 @@ package gomod
 import (
 	"context"
 	"errors"
-	"os"
 	"path/filepath"
-	"regexp"
 	"slices"
 	"strings"
 
 	"github.com/meian/rev-callgraph/internal/progress"
+	"github.com/meian/rev-callgraph/internal/stats"
 	"github.com/meian/rev-callgraph/internal/symbol"
 )
 
@@ -21,6 +20,44 @@ type Module struct {
 	Requires []string
 	// Root は go.mod のディレクトリ（モジュールのルート）
 	Root string
+	// GoVersion は go ディレクティブで指定された Go のバージョン
+	GoVersion string
+	// Deps は require されているモジュールとそのバージョン一覧
+	Deps []Requirement
+	// Replaces は replace ディレクティブの一覧
+	Replaces []Replacement
+	// Version は vendor ディレクトリに取り込まれたバージョン (vendored モジュールのみ)
+	Version string
+	// VendoredBy は vendor ディレクトリに取り込んでいるモジュールのパス (vendored モジュールのみ)
+	// vendored モジュールは読み取り専用のコピーとして扱います
+	VendoredBy string
+}
+
+// IsVendored は他モジュールの vendor ディレクトリに取り込まれたコピーかどうかを判定します
+func (m Module) IsVendored() bool {
+	return m.VendoredBy != ""
+}
+
+// Requirement は go.mod の require 行を表します
+type Requirement struct {
+	// Path はモジュールパス
+	Path string `json:"path"`
+	// Version はバージョン
+	Version string `json:"version,omitempty"`
+	// Indirect は // indirect コメントが付与されているかどうか
+	Indirect bool `json:"indirect,omitempty"`
+}
+
+// Replacement は go.mod の replace 行を表します
+type Replacement struct {
+	// OldPath は置換元のモジュールパス
+	OldPath string `json:"old_path"`
+	// OldVersion は置換元のバージョン (空の場合は全バージョン)
+	OldVersion string `json:"old_version,omitempty"`
+	// NewPath は置換先のモジュールパスまたはディレクトリ
+	NewPath string `json:"new_path"`
+	// NewVersion は置換先のバージョン (ディレクトリ指定の場合は空)
+	NewVersion string `json:"new_version,omitempty"`
 }
 
 // ContainsPackage は pkg がモジュールに含まれるかを判定します
@@ -42,47 +79,32 @@ func (m Module) PackageDir(pkg string) (string, error) {
 	return filepath.Join(m.Root, strings.TrimPrefix(pkg, m.Path+"/")), nil
 }
 
-// HasDefinition は指定された関数/メソッド定義がこのモジュール内に存在するか判定します
-// f.PkgPathがモジュール内に存在しなければfalse, 存在すればパッケージ内の.goファイルを走査して定義を探す
-func (m Module) HasDefinition(f symbol.Function) (bool, error) {
+// Definition は指定された関数/メソッド定義をこのモジュール内から検索します
+// f.PkgPathがモジュール内に存在しない、または定義が見つからない場合は ok=false を返します
+func (m Module) Definition(ctx context.Context, idx *DefinitionIndex, f symbol.Function) (Definition, bool, error) {
+	defer stats.Start(ctx, stats.PhaseDefinition, f.String())()
 	if !m.ContainsPackage(f.PkgPath) {
-		return false, nil
+		return Definition{}, false, nil
 	}
 	pkgDir, err := m.PackageDir(f.PkgPath)
 	if err != nil {
-		return false, err
+		return Definition{}, false, err
 	}
-	files, err := os.ReadDir(pkgDir)
+	defs, err := idx.Package(ctx, f.PkgPath, pkgDir)
 	if err != nil {
-		return false, err
-	}
-	var pat *regexp.Regexp
-	if f.IsMethod() {
-		// メソッド定義: func (recv Type) Method( または func (recv *Type) Method(
-		pat = regexp.MustCompile(`func\s*\([^)]+\*?` + regexp.QuoteMeta(f.TypeName) + `\)\s*` + regexp.QuoteMeta(f.Name) + `\s*\(`)
-	} else {
-		// 関数定義: func FuncName(
-		pat = regexp.MustCompile(`func\s+` + regexp.QuoteMeta(f.Name) + `\s*\(`)
-	}
-	for _, file := range files {
-		if file.IsDir() || !strings.HasSuffix(file.Name(), ".go") {
-			continue
-		}
-		data, err := os.ReadFile(filepath.Join(pkgDir, file.Name()))
-		if err != nil {
-			continue // 読めないファイルはスキップ
-		}
-		if pat.Match(data) {
-			return true, nil
-		}
+		return Definition{}, false, err
 	}
-	return false, nil
+	def, ok := defs.Lookup(f)
+	return def, ok, nil
 }
 
 // ModuleMap はモジュールのマップを表します
 type ModuleMap struct {
 	mmap  map[string]Module
 	paths []string
+	defs  *DefinitionIndex
+	// conflicts は Scan 時に検出したモジュールパスの重複
+	conflicts []Conflict
 }
 
 // NewModuleMap は新しい ModuleMap を作成します
@@ -100,12 +122,24 @@ func NewModuleMap(m map[string]Module) *ModuleMap {
 	return &ModuleMap{
 		mmap:  m,
 		paths: paths,
+		defs:  NewDefinitionIndex(),
 	}
 }
 
+// Get は path で指定したモジュールパスの Module を返します
+// vendored モジュールは対象外です
+func (mm ModuleMap) Get(path string) (Module, bool) {
+	mod, ok := mm.mmap[path]
+	return mod, ok
+}
+
 // FindByPath は pkg で指定したパッケージを含む Module を検索します
+// vendored モジュールは対象外です
 func (mm ModuleMap) FindByPackage(pkg string) (*Module, bool) {
 	for _, p := range mm.paths {
+		if mm.mmap[p].IsVendored() {
+			continue
+		}
 		if mm.mmap[p].ContainsPackage(pkg) {
 			mod := mm.mmap[p]
 			return &mod, true
@@ -119,25 +153,74 @@ func (mm ModuleMap) FindByPackage(pkg string) (*Module, bool) {
 // 見つからない場合は(nil, nil)、エラー時は(nil, err)を返します
 func (mm ModuleMap) FindByFunction(ctx context.Context, f symbol.Function) (*Module, error) {
 	progress.Msgf(ctx, "find module for function: %s", f)
+	mod, _, err := mm.LookupDefinition(ctx, f)
+	return mod, err
+}
+
+// LookupDefinition は f の定義と、それを含む Module を返します
+// 見つからない場合は(nil, nil, nil)、エラー時は(nil, nil, err)を返します
+func (mm ModuleMap) LookupDefinition(ctx context.Context, f symbol.Function) (*Module, *Definition, error) {
 	mod, ok := mm.FindByPackage(f.PkgPath)
 	if !ok {
-		return nil, nil // パッケージを含むモジュールが存在しない
+		return nil, nil, nil // パッケージを含むモジュールが存在しない
 	}
-	exists, err := mod.HasDefinition(f)
+	def, ok, err := mod.Definition(ctx, mm.defIndex(), f)
 	if err != nil {
-		return nil, err // ファイルアクセス等のエラー
+		return nil, nil, err // ファイルアクセス等のエラー
 	}
-	if !exists {
-		return nil, nil // 定義が見つからない
+	if !ok {
+		return nil, nil, nil // 定義が見つからない
 	}
-	return mod, nil
+	return mod, &def, nil
 }
 
-// ReferencedBy は target で指定したモジュールを require しているモジュール一覧を返します
+// Definition は mod 内から f の定義を検索します
+// 解析結果は ModuleMap の定義インデックスで共有されます
+func (mm ModuleMap) Definition(ctx context.Context, mod Module, f symbol.Function) (Definition, bool, error) {
+	return mod.Definition(ctx, mm.defIndex(), f)
+}
+
+// PackageDefs は mod 内の pkgPath のパッケージの定義一覧を返します
+// 解析結果は ModuleMap の定義インデックスで共有されます
+func (mm ModuleMap) PackageDefs(ctx context.Context, mod Module, pkgPath string) (PackageDefs, error) {
+	if !mod.ContainsPackage(pkgPath) {
+		return PackageDefs{}, nil
+	}
+	dir, err := mod.PackageDir(pkgPath)
+	if err != nil {
+		return nil, err
+	}
+	return mm.defIndex().Package(ctx, pkgPath, dir)
+}
+
+// defIndex は定義インデックスを返します
+// ゼロ値の ModuleMap でも利用できるよう、未初期化の場合は都度生成します
+func (mm ModuleMap) defIndex() *DefinitionIndex {
+	if mm.defs == nil {
+		return NewDefinitionIndex()
+	}
+	return mm.defs
+}
+
+// ReferencedBy は target で指定したモジュールを参照しているモジュール一覧を返します
+// m が vendored モジュールの場合は取り込み元モジュールと同じ vendor ディレクトリ内の他のモジュールを、
+// それ以外の場合は m を require しているモジュールと、m のコピーを vendor している
+// vendor ディレクトリ内のモジュールを返します
 func (mm ModuleMap) ReferencedBy(m Module) []Module {
+	if m.IsVendored() {
+		return mm.vendorSiblings(m)
+	}
+	return append(mm.requiredBy(m), mm.vendorUsers(m.Path)...)
+}
+
+// requiredBy は m を require している vendored でないモジュール一覧を返します
+func (mm ModuleMap) requiredBy(m Module) []Module {
 	var result []Module
 	for _, p := range mm.paths {
 		mod := mm.mmap[p]
+		if mod.IsVendored() {
+			continue
+		}
 		for _, req := range mod.Requires {
 			if req == m.Path {
 				result = append(result, mod)
@@ -158,6 +241,11 @@ func (mm ModuleMap) Iter(yield func(string, Module) bool) {
 	}
 }
 
+// Conflicts は Scan 時に検出したモジュールパスの重複一覧を返します
+func (mm ModuleMap) Conflicts() []Conflict {
+	return mm.conflicts
+}
+
 // Len はモジュールの数を返します
 func (mm ModuleMap) Len() int {
 	return len(mm.paths)
@@ -2,19 +2,62 @@ package gomod
 
 import (
 	"context"
+	"fmt"
 	"io/fs"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 
 	"github.com/meian/rev-callgraph/internal/contextutil"
 	"github.com/meian/rev-callgraph/internal/progress"
 	"golang.org/x/mod/modfile"
 )
 
+// ScanOptions は Scan の挙動を指定するオプションです
+type ScanOptions struct {
+	// Prefer はモジュールパスが重複した場合に優先するモジュールのルートディレクトリ一覧
+	Prefer []string
+	// Vendor は各モジュールの vendor ディレクトリに取り込まれたモジュールを
+	// 読み取り専用の vendored モジュールとして含めるかどうか
+	Vendor bool
+}
+
+// 重複解決の理由
+const (
+	// ResolvedByPrefer は --prefer-module の指定で採用したことを表します
+	ResolvedByPrefer = "prefer-module"
+	// ResolvedByWork は go.work の use 指定で採用したことを表します
+	ResolvedByWork = "go.work"
+	// ResolvedByDepth はワークスペースルートに最も近いものを採用したことを表します
+	ResolvedByDepth = "shallowest"
+)
+
+// Conflict は同一のモジュールパスを宣言する go.mod が複数存在することを表します
+type Conflict struct {
+	// Path は重複しているモジュールパス
+	Path string
+	// Roots は同一パスを宣言しているモジュールのルートディレクトリ一覧 (辞書順)
+	Roots []string
+	// Chosen は採用したモジュールのルートディレクトリ
+	Chosen string
+	// Reason は Chosen を採用した理由
+	Reason string
+}
+
 // Scan は root 以下の全ての go.mod を解析し、ModuleMapを返します
-func Scan(ctx context.Context, root string) (*ModuleMap, error) {
-	m := map[string]Module{}
-	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+// 同一のモジュールパスが複数見つかった場合は opts.Prefer, root 直下の go.work,
+// ルートからの深さ (同じ深さなら辞書順) の順に採用するモジュールを決定し、
+// その結果を ModuleMap.Conflicts で参照できるようにします
+// opts.Prefer に見つかったモジュールのルートでないディレクトリが含まれる場合はエラーを返します
+func Scan(ctx context.Context, root string, opts ScanOptions) (*ModuleMap, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	found := map[string][]Module{}
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
@@ -35,15 +78,161 @@ func Scan(ctx context.Context, root string) (*ModuleMap, error) {
 			return err
 		}
 		modPath := mf.Module.Mod.Path
-		reqs := make([]string, 0, len(mf.Require))
-		for _, r := range mf.Require {
-			reqs = append(reqs, r.Mod.Path)
-		}
-		m[modPath] = Module{Path: modPath, Requires: reqs, Root: filepath.Dir(path)}
+		found[modPath] = append(found[modPath], newModule(mf, filepath.Dir(path)))
 		return nil
 	})
 	if err != nil {
 		return nil, err
 	}
-	return NewModuleMap(m), nil
+
+	work, err := workUses(root)
+	if err != nil {
+		return nil, err
+	}
+	modRoots := make(map[string]bool)
+	for _, cands := range found {
+		for _, c := range cands {
+			modRoots[c.Root] = true
+		}
+	}
+	prefer := make([]string, 0, len(opts.Prefer))
+	for _, p := range opts.Prefer {
+		abs, err := filepath.Abs(p)
+		if err != nil {
+			return nil, err
+		}
+		// 指定の誤りで重複の解決が暗黙に変わらないように、モジュールのルートでないディレクトリはエラーとする
+		if !modRoots[abs] {
+			return nil, fmt.Errorf("優先するモジュールのルートディレクトリに go.mod が見つかりません: %s", p)
+		}
+		prefer = append(prefer, abs)
+	}
+
+	m := make(map[string]Module, len(found))
+	var conflicts []Conflict
+	for path, cands := range found {
+		if len(cands) == 1 {
+			m[path] = cands[0]
+			continue
+		}
+		mod, reason := chooseModule(root, cands, prefer, work)
+		m[path] = mod
+		roots := make([]string, 0, len(cands))
+		for _, c := range cands {
+			roots = append(roots, c.Root)
+		}
+		slices.Sort(roots)
+		progress.Msgf(ctx, "  module path conflict: %s %v -> %s (%s)", path, roots, mod.Root, reason)
+		conflicts = append(conflicts, Conflict{Path: path, Roots: roots, Chosen: mod.Root, Reason: reason})
+	}
+	slices.SortFunc(conflicts, func(a, b Conflict) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+	if opts.Vendor {
+		for _, owner := range slices.Collect(maps.Values(m)) {
+			vendored, err := scanVendor(owner)
+			if err != nil {
+				return nil, err
+			}
+			for _, v := range vendored {
+				progress.Msgf(ctx, "  detected vendored module: %s in %s", v.Path, owner.Path)
+				m[vendorKey(v)] = v
+			}
+		}
+	}
+	mm := NewModuleMap(m)
+	mm.conflicts = conflicts
+	return mm, nil
+}
+
+// newModule は解析済みの go.mod から Module を構築します
+func newModule(mf *modfile.File, root string) Module {
+	mod := Module{
+		Path:     mf.Module.Mod.Path,
+		Root:     root,
+		Requires: make([]string, 0, len(mf.Require)),
+		Deps:     make([]Requirement, 0, len(mf.Require)),
+	}
+	if mf.Go != nil {
+		mod.GoVersion = mf.Go.Version
+	}
+	for _, r := range mf.Require {
+		mod.Requires = append(mod.Requires, r.Mod.Path)
+		mod.Deps = append(mod.Deps, Requirement{Path: r.Mod.Path, Version: r.Mod.Version, Indirect: r.Indirect})
+	}
+	for _, r := range mf.Replace {
+		mod.Replaces = append(mod.Replaces, Replacement{
+			OldPath:    r.Old.Path,
+			OldVersion: r.Old.Version,
+			NewPath:    r.New.Path,
+			NewVersion: r.New.Version,
+		})
+	}
+	return mod
+}
+
+// chooseModule は同一パスの候補から採用するモジュールとその理由を返します
+func chooseModule(root string, cands []Module, prefer, work []string) (Module, string) {
+	for _, p := range prefer {
+		for _, c := range cands {
+			if c.Root == p {
+				return c, ResolvedByPrefer
+			}
+		}
+	}
+	for _, w := range work {
+		for _, c := range cands {
+			if c.Root == w {
+				return c, ResolvedByWork
+			}
+		}
+	}
+	sorted := slices.Clone(cands)
+	slices.SortFunc(sorted, func(a, b Module) int {
+		da, db := depth(root, a.Root), depth(root, b.Root)
+		if da != db {
+			return da - db
+		}
+		return strings.Compare(a.Root, b.Root)
+	})
+	return sorted[0], ResolvedByDepth
+}
+
+// depth は root から dir までのディレクトリ階層の深さを返します
+func depth(root, dir string) int {
+	rel, err := filepath.Rel(root, dir)
+	if err != nil || rel == "." {
+		return 0
+	}
+	return strings.Count(filepath.ToSlash(rel), "/") + 1
+}
+
+// workUses は root 直下の go.work が use しているディレクトリの絶対パス一覧を返します
+// go.work が存在しない場合は nil を返します
+func workUses(root string) ([]string, error) {
+	path := filepath.Join(root, "go.work")
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	wf, err := modfile.ParseWork(path, data, nil)
+	if err != nil {
+		return nil, err
+	}
+	uses := make([]string, 0, len(wf.Use))
+	for _, u := range wf.Use {
+		dir := filepath.FromSlash(u.Path)
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(root, dir)
+		}
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			return nil, err
+		}
+		uses = append(uses, abs)
+	}
+	return uses, nil
 }
@@ -3,11 +3,13 @@ package gomod_test
 import (
 	"context"
 	"errors"
+	"os"
 	"path/filepath"
 	"testing"
 
 	"github.com/meian/rev-callgraph/internal/gomod"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestScan_ContextCancellation(t *testing.T) {
@@ -19,7 +21,7 @@ func TestScan_ContextCancellation(t *testing.T) {
 	cancel() // 直ちにキャンセル
 
 	// Scan を実行（キャンセルされたコンテキストで）
-	_, err := gomod.Scan(ctx, testdataPath)
+	_, err := gomod.Scan(ctx, testdataPath, gomod.ScanOptions{})
 
 	// キャンセルエラーが返されることを確認
 	assert.True(t, errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled),
@@ -31,7 +33,53 @@ func TestScan_NormalOperation(t *testing.T) {
 	ctx := context.Background()
 	testdataPath := filepath.Join("..", "..", "testdata")
 
-	modules, err := gomod.Scan(ctx, testdataPath)
+	modules, err := gomod.Scan(ctx, testdataPath, gomod.ScanOptions{})
 	assert.NoError(t, err, "予期しないエラー")
 	assert.NotNil(t, modules, "modules が nil です")
 }
+
+func TestScan_Conflict(t *testing.T) {
+	root := t.TempDir()
+	writeModule := func(dir string) {
+		require.NoError(t, os.MkdirAll(filepath.Join(root, dir), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(root, dir, "go.mod"), []byte("module example.com/dup\n\ngo 1.24\n"), 0644))
+	}
+	writeModule("a")
+	writeModule(filepath.Join("tmp", "old"))
+	writeModule("z")
+
+	ctx := context.Background()
+
+	// 指定がなければワークスペースルートに近いもの (同じ深さなら辞書順) を採用
+	mods, err := gomod.Scan(ctx, root, gomod.ScanOptions{})
+	require.NoError(t, err)
+	require.Len(t, mods.Conflicts(), 1)
+	c := mods.Conflicts()[0]
+	assert.Equal(t, "example.com/dup", c.Path)
+	assert.Equal(t, []string{filepath.Join(root, "a"), filepath.Join(root, "tmp", "old"), filepath.Join(root, "z")}, c.Roots)
+	assert.Equal(t, filepath.Join(root, "a"), c.Chosen)
+	assert.Equal(t, gomod.ResolvedByDepth, c.Reason)
+
+	// go.work の use を優先
+	require.NoError(t, os.WriteFile(filepath.Join(root, "go.work"), []byte("go 1.24\n\nuse ./z\n"), 0644))
+	mods, err = gomod.Scan(ctx, root, gomod.ScanOptions{})
+	require.NoError(t, err)
+	mod, ok := mods.FindByPackage("example.com/dup")
+	require.True(t, ok)
+	assert.Equal(t, filepath.Join(root, "z"), mod.Root)
+	assert.Equal(t, gomod.ResolvedByWork, mods.Conflicts()[0].Reason)
+
+	// --prefer-module は go.work より優先
+	mods, err = gomod.Scan(ctx, root, gomod.ScanOptions{Prefer: []string{filepath.Join(root, "tmp", "old")}})
+	require.NoError(t, err)
+	mod, ok = mods.FindByPackage("example.com/dup")
+	require.True(t, ok)
+	assert.Equal(t, filepath.Join(root, "tmp", "old"), mod.Root)
+	assert.Equal(t, gomod.ResolvedByPrefer, mods.Conflicts()[0].Reason)
+
+	// モジュールのルートでないディレクトリの指定はエラーとする
+	_, err = gomod.Scan(ctx, root, gomod.ScanOptions{Prefer: []string{filepath.Join(root, "tmp", "typo")}})
+	assert.ErrorContains(t, err, filepath.Join(root, "tmp", "typo"))
+	_, err = gomod.Scan(ctx, root, gomod.ScanOptions{Prefer: []string{filepath.Join(root, "tmp")}})
+	assert.Error(t, err)
+}
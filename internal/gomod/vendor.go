@@ -0,0 +1,133 @@
+package gomod
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/meian/rev-callgraph/internal/progress"
+	"github.com/meian/rev-callgraph/internal/symbol"
+)
+
+// vendorKey は vendored モジュールを ModuleMap に登録する際のキーを返します
+// 取り込み元モジュールのパスを前置することで、同一パスの vendored でないモジュールと区別します
+func vendorKey(m Module) string {
+	return m.VendoredBy + "/vendor/" + m.Path
+}
+
+// scanVendor は owner の vendor/modules.txt を解析し、vendor ディレクトリに取り込まれたモジュール一覧を返します
+// vendor/modules.txt が存在しない場合は nil を返します
+func scanVendor(owner Module) ([]Module, error) {
+	vendorDir := filepath.Join(owner.Root, "vendor")
+	f, err := os.Open(filepath.Join(vendorDir, "modules.txt"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var mods []Module
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// モジュール行: "# path version" または "# path version => replacement [version]"
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "# ") {
+			continue
+		}
+		fields := strings.Fields(strings.TrimPrefix(line, "# "))
+		if len(fields) == 0 {
+			continue
+		}
+		mod := Module{
+			Path:       fields[0],
+			Root:       filepath.Join(vendorDir, filepath.FromSlash(fields[0])),
+			VendoredBy: owner.Path,
+		}
+		if len(fields) > 1 && fields[1] != "=>" {
+			mod.Version = fields[1]
+		}
+		// パッケージが一つも取り込まれていないモジュールはディレクトリが存在しない
+		if fi, err := os.Stat(mod.Root); err != nil || !fi.IsDir() {
+			continue
+		}
+		mods = append(mods, mod)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return mods, nil
+}
+
+// vendorSiblings は vendored モジュール m を参照し得るモジュールとして、
+// 取り込み元モジュールと同じ vendor ディレクトリ内の他のモジュールを返します
+func (mm ModuleMap) vendorSiblings(m Module) []Module {
+	var result []Module
+	for _, p := range mm.paths {
+		mod := mm.mmap[p]
+		switch {
+		case !mod.IsVendored() && mod.Path == m.VendoredBy:
+			result = append(result, mod)
+		case mod.IsVendored() && mod.VendoredBy == m.VendoredBy && mod.Path != m.Path:
+			result = append(result, mod)
+		}
+	}
+	return result
+}
+
+// vendorUsers は path のコピーを vendor しているモジュールについて、
+// 同じ vendor ディレクトリ内の全ての vendored モジュールを返します
+func (mm ModuleMap) vendorUsers(path string) []Module {
+	owners := map[string]struct{}{}
+	for _, p := range mm.paths {
+		mod := mm.mmap[p]
+		if mod.IsVendored() && mod.Path == path {
+			owners[mod.VendoredBy] = struct{}{}
+		}
+	}
+	if len(owners) == 0 {
+		return nil
+	}
+	var result []Module
+	for _, p := range mm.paths {
+		mod := mm.mmap[p]
+		if _, ok := owners[mod.VendoredBy]; ok && mod.IsVendored() {
+			result = append(result, mod)
+		}
+	}
+	return result
+}
+
+// FindByFunctionIn は vendoredBy で指定したモジュールの vendor ディレクトリ内から
+// 関数/メソッド定義が存在する vendored モジュールを検索します
+// vendoredBy が空の場合は FindByFunction と同じです
+func (mm ModuleMap) FindByFunctionIn(ctx context.Context, f symbol.Function, vendoredBy string) (*Module, error) {
+	if vendoredBy == "" {
+		return mm.FindByFunction(ctx, f)
+	}
+	progress.Msgf(ctx, "find vendored module for function: %s in %s", f, vendoredBy)
+	var found *Module
+	for _, p := range mm.paths {
+		mod := mm.mmap[p]
+		if mod.VendoredBy != vendoredBy || !mod.ContainsPackage(f.PkgPath) {
+			continue
+		}
+		if found == nil || len(mod.Path) > len(found.Path) {
+			found = &mod
+		}
+	}
+	if found == nil {
+		return nil, nil
+	}
+	_, ok, err := found.Definition(ctx, mm.defIndex(), f)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, nil
+	}
+	return found, nil
+}
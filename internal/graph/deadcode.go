@@ -0,0 +1,19 @@
+package graph
+
+// DeadModule はモジュール内の到達できない関数をパッケージごとにまとめたものです
+type DeadModule struct {
+	// Module はモジュールのパス
+	Module string
+	// Packages は到達できない関数を含むパッケージ
+	Packages []DeadPackage
+}
+
+// DeadPackage はパッケージ内の到達できない関数を表します
+type DeadPackage struct {
+	// Package はパッケージのパス
+	Package string
+	// Dir はパッケージのディレクトリ
+	Dir string
+	// Functions は到達できない関数/メソッドのノードを定義位置の順に並べたもの
+	Functions []*Node
+}
@@ -0,0 +1,31 @@
+package graph
+
+// EntryKind はエントリポイントの種別を表します
+type EntryKind string
+
+const (
+	// EntryMain は main パッケージの main 関数を表します
+	EntryMain EntryKind = "main"
+	// EntryInit はパッケージの init 関数を表します
+	EntryInit EntryKind = "init"
+	// EntryTest は _test.go 内のテスト・ベンチマーク・ファズ・Example 関数を表します
+	EntryTest EntryKind = "test"
+	// EntryHandler は HTTP ハンドラ (ServeHTTP メソッド) を表します
+	EntryHandler EntryKind = "handler"
+	// EntryExported は main 以外のパッケージの公開された関数・メソッドを表します
+	EntryExported EntryKind = "exported"
+	// EntryNoCallers は上記のいずれでもなく、呼び出し元が見つからなかったノードを表します
+	EntryNoCallers EntryKind = "no-callers"
+)
+
+// EntryPoint は起点ノードに到達するエントリポイントを表します
+type EntryPoint struct {
+	// Node はエントリポイントのノード
+	Node *Node
+	// Kind はエントリポイントの種別
+	Kind EntryKind
+	// Dir はノードを含むパッケージのディレクトリ
+	Dir string
+	// Path はエントリポイントから起点ノードに至る経路の一例 (最短の経路)
+	Path Path
+}
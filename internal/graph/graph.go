@@ -0,0 +1,208 @@
+// Package graph はコールグラフの内部表現となる、ノードとエッジのグラフを提供します
+package graph
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Kind はノードの種別を表します
+type Kind string
+
+const (
+	// KindFunc は関数を表します
+	KindFunc Kind = "func"
+	// KindMethod はメソッドを表します
+	KindMethod Kind = "method"
+	// KindPackage はパッケージを表します
+	KindPackage Kind = "package"
+	// KindModule はモジュールを表します
+	KindModule Kind = "module"
+)
+
+// Position はソースコード上の位置を表します
+type Position struct {
+	// File はファイルパス
+	File string `json:"file"`
+	// Line は行番号 (1始まり)
+	Line int `json:"line"`
+	// Column は列番号 (1始まり)
+	Column int `json:"column"`
+}
+
+// String は位置を file:line:column 形式で返します
+func (p Position) String() string {
+	return fmt.Sprintf("%s:%d:%d", p.File, p.Line, p.Column)
+}
+
+// Node はグラフのノードを表します
+type Node struct {
+	// ID はグラフ内でノードを一意に識別する文字列
+	ID string `json:"id"`
+	// Name は関数名・パッケージパス・モジュールパス
+	Name string `json:"name"`
+	// Kind はノードの種別
+	Kind Kind `json:"kind,omitempty"`
+	// Module はノードを含むモジュールのパス
+	Module string `json:"module,omitempty"`
+	// Pos は定義位置
+	Pos *Position `json:"pos,omitempty"`
+	// Main はmainパッケージかどうか
+	Main bool `json:"main,omitempty"`
+	// Vendor は vendor ディレクトリに取り込まれたコピー内のノードである場合に、取り込み元モジュールのパス
+	Vendor string `json:"vendored_by,omitempty"`
+	// Truncated は探索が中断されて呼び出し元を展開できなかった場合にその理由
+	Truncated Truncation `json:"truncated,omitempty"`
+	// External はワークスペース外の関数の場合に、標準ライブラリ (stdlib) か外部モジュール (external) かを表す
+	External string `json:"external,omitempty"`
+	// Cycle は循環 (強連結成分) に含まれるノードの場合に、その循環の番号 (1始まり)
+	Cycle int `json:"cycle,omitempty"`
+}
+
+// Truncation は探索が中断された理由を表します
+type Truncation string
+
+const (
+	// TruncatedTimeout はタイムアウトにより中断されたことを表します
+	TruncatedTimeout Truncation = "timeout"
+	// TruncatedInterrupted は割り込み (SIGINT 等) により中断されたことを表します
+	TruncatedInterrupted Truncation = "interrupted"
+)
+
+// NodeID は名前と取り込み元モジュールからノードのIDを返します
+// vendored のコピーは取り込み元モジュールを付与して同名のノードと区別します
+func NodeID(name, vendoredBy string) string {
+	if vendoredBy != "" {
+		return name + " (vendored by " + vendoredBy + ")"
+	}
+	return name
+}
+
+// Edge は呼び出し元から呼び出し先へのエッジを表します
+// モジュールの依存グラフでは Caller が依存先、Callee が依存元となります
+type Edge struct {
+	// Caller は呼び出し元ノードのID
+	Caller string `json:"caller"`
+	// Callee は呼び出し先ノードのID
+	Callee string `json:"callee"`
+}
+
+// Graph は起点ノードから呼び出し元方向 (Forward の場合は呼び出し先方向) に辿ったノードとエッジの集合です
+// Nodes と Edges は追加された順序を保持し、同じノード・エッジは一度だけ保持します
+type Graph struct {
+	// Root は起点ノードのID
+	Root string `json:"root"`
+	// Forward は起点ノードから呼び出し先方向に辿ったグラフかどうか
+	Forward bool `json:"forward,omitempty"`
+	// Nodes はノードの一覧
+	Nodes []*Node `json:"nodes"`
+	// Edges はエッジの一覧
+	Edges []Edge `json:"edges"`
+
+	nodes   map[string]*Node
+	edges   map[Edge]struct{}
+	callers map[string][]string
+	callees map[string][]string
+	sites   map[Edge][]Position
+}
+
+// New は root を起点ノードとするグラフを作成します
+// root が nil の場合は起点ノードのないグラフ (ワークスペース全体のグラフなど) を作成します
+func New(root *Node) *Graph {
+	g := &Graph{
+		Nodes:   []*Node{},
+		Edges:   []Edge{},
+		nodes:   make(map[string]*Node),
+		edges:   make(map[Edge]struct{}),
+		callers: make(map[string][]string),
+		callees: make(map[string][]string),
+		sites:   make(map[Edge][]Position),
+	}
+	if root != nil {
+		g.Root = root.ID
+		g.AddNode(root)
+	}
+	return g
+}
+
+// AddNode はノードを追加します
+// 同じIDのノードが既に存在する場合は追加せず、既存のノードと false を返します
+func (g *Graph) AddNode(n *Node) (*Node, bool) {
+	if exist, ok := g.nodes[n.ID]; ok {
+		return exist, false
+	}
+	g.nodes[n.ID] = n
+	g.Nodes = append(g.Nodes, n)
+	return n, true
+}
+
+// AddEdge は caller から callee へのエッジを追加します
+// 同じエッジが既に存在する場合は追加せず false を返します
+func (g *Graph) AddEdge(caller, callee string) bool {
+	e := Edge{Caller: caller, Callee: callee}
+	if _, ok := g.edges[e]; ok {
+		return false
+	}
+	g.edges[e] = struct{}{}
+	g.Edges = append(g.Edges, e)
+	g.callers[callee] = append(g.callers[callee], caller)
+	g.callees[caller] = append(g.callees[caller], callee)
+	return true
+}
+
+// AddSite は caller から callee へのエッジに呼び出し箇所 pos を追加します
+// 同じ呼び出し箇所が既に存在する場合は追加しません
+func (g *Graph) AddSite(caller, callee string, pos Position) {
+	e := Edge{Caller: caller, Callee: callee}
+	if slices.Contains(g.sites[e], pos) {
+		return
+	}
+	g.sites[e] = append(g.sites[e], pos)
+}
+
+// Sites は caller から callee へのエッジの呼び出し箇所を、追加された順に返します
+func (g *Graph) Sites(caller, callee string) []Position {
+	return g.sites[Edge{Caller: caller, Callee: callee}]
+}
+
+// Node は id のノードを返します
+func (g *Graph) Node(id string) (*Node, bool) {
+	n, ok := g.nodes[id]
+	return n, ok
+}
+
+// RootNode は起点ノードを返します
+func (g *Graph) RootNode() *Node {
+	return g.nodes[g.Root]
+}
+
+// Callees は id のノードの呼び出し先ノードを、エッジが追加された順に返します
+func (g *Graph) Callees(id string) []*Node {
+	ids := g.callees[id]
+	nodes := make([]*Node, 0, len(ids))
+	for _, c := range ids {
+		nodes = append(nodes, g.nodes[c])
+	}
+	return nodes
+}
+
+// Truncated は呼び出し元を展開できなかったノードを追加された順に返します
+func (g *Graph) Truncated() []*Node {
+	var nodes []*Node
+	for _, n := range g.Nodes {
+		if n.Truncated != "" {
+			nodes = append(nodes, n)
+		}
+	}
+	return nodes
+}
+
+// Callers は id のノードの呼び出し元ノードを、エッジが追加された順に返します
+func (g *Graph) Callers(id string) []*Node {
+	ids := g.callers[id]
+	nodes := make([]*Node, 0, len(ids))
+	for _, c := range ids {
+		nodes = append(nodes, g.nodes[c])
+	}
+	return nodes
+}
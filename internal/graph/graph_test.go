@@ -0,0 +1,131 @@
+package graph_test
+
+import (
+	"testing"
+
+	"github.com/meian/rev-callgraph/internal/graph"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGraph(t *testing.T) {
+	g := graph.New(&graph.Node{ID: "T", Name: "T"})
+	for _, id := range []string{"A", "B", "C"} {
+		_, added := g.AddNode(&graph.Node{ID: id, Name: id})
+		assert.True(t, added)
+	}
+	n, added := g.AddNode(&graph.Node{ID: "A", Name: "other"})
+	assert.False(t, added, "同じIDのノードは追加されません")
+	assert.Equal(t, "A", n.Name)
+
+	// T <- A, T <- B, A <- C, B <- C, C <- T (循環)
+	assert.True(t, g.AddEdge("A", "T"))
+	assert.True(t, g.AddEdge("B", "T"))
+	assert.True(t, g.AddEdge("C", "A"))
+	assert.True(t, g.AddEdge("C", "B"))
+	assert.True(t, g.AddEdge("T", "C"))
+	assert.False(t, g.AddEdge("A", "T"), "同じエッジは追加されません")
+
+	assert.Len(t, g.Nodes, 4)
+	assert.Len(t, g.Edges, 5)
+	assert.Equal(t, []*graph.Node{{ID: "C", Name: "C"}}, g.Callers("A"))
+
+	// 合流するノードは経路ごとに展開し、経路上の循環は Cycled で打ち切る
+	assert.Equal(t, &graph.TreeNode{
+		Name: "T",
+		Callers: []*graph.TreeNode{
+			{Name: "A", Callers: []*graph.TreeNode{
+				{Name: "C", Callers: []*graph.TreeNode{{Name: "T", Cycled: true}}},
+			}},
+			{Name: "B", Callers: []*graph.TreeNode{
+				{Name: "C", Callers: []*graph.TreeNode{{Name: "T", Cycled: true}}},
+			}},
+		},
+	}, g.Tree(0))
+
+	assert.Equal(t, &graph.TreeNode{
+		Name: "T",
+		Callers: []*graph.TreeNode{
+			{Name: "A"},
+			{Name: "B"},
+		},
+	}, g.Tree(1))
+}
+
+func TestGraph_ShortestPath(t *testing.T) {
+	g := graph.New(&graph.Node{ID: "T", Name: "T"})
+	for _, id := range []string{"A", "B", "C", "D"} {
+		g.AddNode(&graph.Node{ID: id, Name: id})
+	}
+	// T <- A <- B <- C, T <- C (近道), D は到達不能
+	g.AddEdge("A", "T")
+	g.AddEdge("B", "A")
+	g.AddEdge("C", "B")
+	g.AddEdge("C", "T")
+	g.AddSite("C", "T", graph.Position{File: "c.go", Line: 3, Column: 2})
+	g.AddSite("C", "T", graph.Position{File: "c.go", Line: 3, Column: 2})
+
+	assert.Equal(t, graph.Path{"C", "T"}, g.ShortestPath("C"))
+	assert.Equal(t, graph.Path{"B", "A", "T"}, g.ShortestPath("B"))
+	assert.Equal(t, 2, g.ShortestPath("B").Depth())
+	assert.Equal(t, graph.Path{"T"}, g.ShortestPath("T"))
+	assert.Nil(t, g.ShortestPath("D"))
+	assert.Nil(t, g.ShortestPath("X"))
+	assert.Equal(t, []graph.Position{{File: "c.go", Line: 3, Column: 2}}, g.Sites("C", "T"), "同じ呼び出し箇所は一度だけ保持されます")
+}
+
+func TestGraph_Paths(t *testing.T) {
+	g := graph.New(&graph.Node{ID: "T", Name: "T"})
+	for _, id := range []string{"A", "B", "C", "F"} {
+		g.AddNode(&graph.Node{ID: id, Name: id})
+	}
+	// F -> A -> T, F -> B -> T, B -> C -> B (循環), C -> T
+	g.AddEdge("A", "T")
+	g.AddEdge("B", "T")
+	g.AddEdge("C", "T")
+	g.AddEdge("F", "A")
+	g.AddEdge("F", "B")
+	g.AddEdge("B", "C")
+	g.AddEdge("C", "B")
+
+	// 循環は同じノードを二度通らない経路のみ列挙する
+	assert.Equal(t, []graph.Path{
+		{"F", "A", "T"},
+		{"F", "B", "T"},
+		{"F", "B", "C", "T"},
+	}, g.Paths("F", 0))
+	assert.Equal(t, []graph.Path{{"F", "A", "T"}, {"F", "B", "T"}}, g.Paths("F", 2))
+	assert.Equal(t, []graph.Path{{"T"}}, g.Paths("T", 0))
+	assert.Nil(t, g.Paths("X", 0))
+}
+
+func TestGraph_Cycles(t *testing.T) {
+	g := graph.New(&graph.Node{ID: "T", Name: "T"})
+	for _, id := range []string{"A", "B", "C", "D", "E"} {
+		g.AddNode(&graph.Node{ID: id, Name: id})
+	}
+	// T <- A <- B <- A (相互再帰), B <- C, C <- C (自己再帰), D <- E (循環なし)
+	g.AddEdge("A", "T")
+	g.AddEdge("B", "A")
+	g.AddEdge("A", "B")
+	g.AddEdge("C", "B")
+	g.AddEdge("C", "C")
+	g.AddEdge("E", "D")
+
+	cycles := g.MarkCycles()
+	assert.Equal(t, []graph.Cycle{
+		{ID: 1, Members: []string{"A", "B"}, Edges: []graph.Edge{{Caller: "B", Callee: "A"}, {Caller: "A", Callee: "B"}}},
+		{ID: 2, Members: []string{"C"}, Edges: []graph.Edge{{Caller: "C", Callee: "C"}}},
+	}, cycles)
+	for id, want := range map[string]int{"T": 0, "A": 1, "B": 1, "C": 2, "D": 0, "E": 0} {
+		n, _ := g.Node(id)
+		assert.Equal(t, want, n.Cycle, id)
+	}
+
+	// 成分の間の呼び出しは番号の大きい成分から小さい成分に向かう
+	comp, n := g.Components()
+	assert.Equal(t, 5, n)
+	assert.Equal(t, comp["A"], comp["B"])
+	assert.Less(t, comp["T"], comp["A"])
+	assert.Less(t, comp["B"], comp["C"])
+	assert.Less(t, comp["D"], comp["E"])
+}
@@ -0,0 +1,29 @@
+package graph
+
+// Impact は変更された関数の影響範囲を表します
+type Impact struct {
+	// Changed は変更された関数のノード
+	Changed []*Node
+	// EntryPoints は変更された関数に到達するエントリポイント
+	EntryPoints []ImpactEntry
+	// Packages は変更された関数とその呼び出し元を含むパッケージのパス
+	Packages []string
+	// Binaries は影響を受ける main パッケージ
+	Binaries []Binary
+}
+
+// ImpactEntry は変更された関数に到達するエントリポイントを表します
+// 複数の変更された関数に到達する場合は、変更された関数ごとに別のエントリとなります
+type ImpactEntry struct {
+	EntryPoint
+	// Changed は Path の末尾にある変更された関数のノードID
+	Changed string
+}
+
+// Binary は影響を受ける main パッケージを表します
+type Binary struct {
+	// Package は main パッケージのパス
+	Package string
+	// Dir は main パッケージのディレクトリ
+	Dir string
+}
@@ -0,0 +1,15 @@
+package graph
+
+// NodeStats はノードの呼び出し元に関する統計を表します
+type NodeStats struct {
+	// Node は対象のノード
+	Node *Node
+	// DirectCallers は直接の呼び出し元の数 (自身を除く)
+	DirectCallers int
+	// TransitiveCallers は呼び出し元を辿って到達できるノードの数 (自身を除く)
+	TransitiveCallers int
+	// EntryPoints は自身と推移的な呼び出し元のうち、エントリポイント (呼び出し元のない末端のノード) の数
+	EntryPoints int
+	// Depth は起点ノードからの最短距離 (起点ノードのないグラフの場合は -1)
+	Depth int
+}
@@ -0,0 +1,89 @@
+package graph
+
+import "slices"
+
+// Path は呼び出し元のノードから起点ノードに至る経路です
+// ノードIDを呼び出し元から順に並べ、末尾が起点ノードとなります
+type Path []string
+
+// Depth は経路の呼び出しの数を返します
+func (p Path) Depth() int {
+	return len(p) - 1
+}
+
+// ShortestPath は id のノードから起点ノードに至る最短の経路を返します
+// 同じ長さの経路が複数ある場合は、エッジが追加された順で先に見つかった経路を返します
+// 起点ノードに到達できない場合は nil を返します
+func (g *Graph) ShortestPath(id string) Path {
+	return g.ShortestPathsTo(g.Root)(id)
+}
+
+// ShortestPathsTo は to のノードから呼び出し元方向に幅優先で辿り、
+// 各ノードから to のノードに至る最短の経路を返す関数を返します
+// 複数の起点から構築したグラフで、起点ノード以外のノードへの経路をまとめて求める場合に使います
+// 返す関数は to に到達できないノードに対して nil を返します
+func (g *Graph) ShortestPathsTo(to string) func(id string) Path {
+	// 各ノードの to 側の呼び出し先を記録する
+	callee := make(map[string]string)
+	if _, ok := g.nodes[to]; ok {
+		callee[to] = ""
+		queue := []string{to}
+		for len(queue) > 0 {
+			cur := queue[0]
+			queue = queue[1:]
+			for _, c := range g.callers[cur] {
+				if _, ok := callee[c]; ok {
+					continue
+				}
+				callee[c] = cur
+				queue = append(queue, c)
+			}
+		}
+	}
+	return func(id string) Path {
+		if _, ok := callee[id]; !ok {
+			return nil
+		}
+		var p Path
+		for n := id; n != ""; n = callee[n] {
+			p = append(p, n)
+		}
+		return p
+	}
+}
+
+// Paths は from のノードから起点ノードに至る単純な経路 (同じノードを二度通らない経路) を返します
+// 経路は呼び出し先をエッジが追加された順に深さ優先で辿って見つかった順に並びます
+// limit>0 の場合は最初の limit 件で打ち切ります
+func (g *Graph) Paths(from string, limit int) []Path {
+	if _, ok := g.nodes[from]; !ok {
+		return nil
+	}
+	var (
+		paths  []Path
+		cur    Path
+		onPath = make(map[string]struct{})
+	)
+	var walk func(id string) bool
+	walk = func(id string) bool {
+		cur = append(cur, id)
+		defer func() { cur = cur[:len(cur)-1] }()
+		if id == g.Root {
+			paths = append(paths, slices.Clone(cur))
+			return limit > 0 && len(paths) >= limit
+		}
+		onPath[id] = struct{}{}
+		defer delete(onPath, id)
+		for _, c := range g.callees[id] {
+			if _, ok := onPath[c]; ok {
+				continue
+			}
+			if walk(c) {
+				return true
+			}
+		}
+		return false
+	}
+	walk(from)
+	return paths
+}
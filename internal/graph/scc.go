@@ -0,0 +1,100 @@
+package graph
+
+// Cycle はグラフ内の循環 (2つ以上のノードからなる強連結成分、または自身を呼び出すノード) を表します
+type Cycle struct {
+	// ID は循環の番号 (1始まり)
+	ID int
+	// Members は循環に含まれるノードのIDを、ノードが追加された順に並べたもの
+	Members []string
+	// Edges は循環を構成する (メンバー同士の) 呼び出しを、エッジが追加された順に並べたもの
+	Edges []Edge
+}
+
+// Cycles はグラフの強連結成分のうち、循環となるものを返します
+// 循環はメンバーのうち最初に追加されたノードの順に並び、1から順に番号を付けます
+func (g *Graph) Cycles() []Cycle {
+	comp, _ := g.Components()
+
+	// 成分ごとにメンバーと内部のエッジをまとめ、循環となる成分のみ番号を付ける
+	members := make(map[int][]string)
+	for _, n := range g.Nodes {
+		members[comp[n.ID]] = append(members[comp[n.ID]], n.ID)
+	}
+	edges := make(map[int][]Edge)
+	for _, e := range g.Edges {
+		if comp[e.Caller] == comp[e.Callee] {
+			edges[comp[e.Caller]] = append(edges[comp[e.Caller]], e)
+		}
+	}
+	var cycles []Cycle
+	seen := make(map[int]bool)
+	for _, n := range g.Nodes {
+		c := comp[n.ID]
+		if seen[c] || len(edges[c]) == 0 {
+			continue
+		}
+		seen[c] = true
+		cycles = append(cycles, Cycle{ID: len(cycles) + 1, Members: members[c], Edges: edges[c]})
+	}
+	return cycles
+}
+
+// Components は Tarjan のアルゴリズムでグラフの強連結成分を求め、各ノードの成分の番号と成分の数を返します
+// 番号は1始まりで、成分の間の呼び出しは常に番号の大きい成分から小さい成分に向かいます
+// (番号の降順に並べると呼び出し元を先にしたトポロジカル順になります)
+func (g *Graph) Components() (map[string]int, int) {
+	var (
+		index   = make(map[string]int, len(g.Nodes))
+		lowlink = make(map[string]int, len(g.Nodes))
+		onStack = make(map[string]bool)
+		stack   []string
+		comp    = make(map[string]int)
+		ncomp   int
+	)
+	var connect func(id string)
+	connect = func(id string) {
+		index[id] = len(index)
+		lowlink[id] = index[id]
+		stack = append(stack, id)
+		onStack[id] = true
+		for _, c := range g.callees[id] {
+			if _, ok := index[c]; !ok {
+				connect(c)
+				lowlink[id] = min(lowlink[id], lowlink[c])
+			} else if onStack[c] {
+				lowlink[id] = min(lowlink[id], index[c])
+			}
+		}
+		if lowlink[id] != index[id] {
+			return
+		}
+		// id を根とする強連結成分をスタックから取り出す
+		ncomp++
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[top] = false
+			comp[top] = ncomp
+			if top == id {
+				break
+			}
+		}
+	}
+	for _, n := range g.Nodes {
+		if _, ok := index[n.ID]; !ok {
+			connect(n.ID)
+		}
+	}
+	return comp, ncomp
+}
+
+// MarkCycles はグラフの循環を求め、循環に含まれるノードの Cycle に循環の番号を設定します
+func (g *Graph) MarkCycles() []Cycle {
+	cycles := g.Cycles()
+	for _, c := range cycles {
+		for _, id := range c.Members {
+			g.nodes[id].Cycle = c.ID
+		}
+	}
+	return cycles
+}
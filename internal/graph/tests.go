@@ -0,0 +1,47 @@
+package graph
+
+import "strings"
+
+// TestPackage は同じパッケージディレクトリのテスト関数を表します
+type TestPackage struct {
+	// Package はテスト関数を含むパッケージのパス (外部テストパッケージの場合も _test を除いたパス)
+	Package string
+	// Dir はパッケージのディレクトリ
+	Dir string
+	// ModuleDir はパッケージを含むモジュールのルートディレクトリ (ワークスペースのルートからの相対パス)
+	ModuleDir string
+	// Pattern は go test に渡すパッケージのパターン (モジュールのルートからの相対パス)
+	Pattern string
+	// Tests は Test, Fuzz, Example で始まるテスト関数の名前
+	Tests []string
+	// Benchmarks は Benchmark で始まるベンチマーク関数の名前
+	Benchmarks []string
+}
+
+// Command はテスト関数のみを実行する go test のコマンドラインを返します
+// モジュールのルートがワークスペースのルートと異なる場合は go -C でモジュールのルートに移動します
+func (p TestPackage) Command() string {
+	args := []string{"go"}
+	if p.ModuleDir != "." {
+		args = append(args, "-C", shellQuote(p.ModuleDir))
+	}
+	args = append(args, "test", shellQuote(p.Pattern))
+	run := "^$"
+	if len(p.Tests) > 0 {
+		run = "^(" + strings.Join(p.Tests, "|") + ")$"
+	}
+	args = append(args, "-run", shellQuote(run))
+	if len(p.Benchmarks) > 0 {
+		args = append(args, "-bench", shellQuote("^("+strings.Join(p.Benchmarks, "|")+")$"))
+	}
+	return strings.Join(args, " ")
+}
+
+// shellQuote は s を POSIX シェルの単一引用符で囲みます
+// 引用が不要な文字のみの場合はそのまま返します
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,91 @@
+package graph
+
+// TreeNode はグラフを起点ノードから経路ごとに展開したツリーのノードを表します
+type TreeNode struct {
+	// Name は関数名を表す
+	Name string `json:"name"`
+	// Callers は呼び出し元ノードのスライスを表す
+	Callers []*TreeNode `json:"callers,omitempty"`
+	// Callees は呼び出し先ノードのスライスを表す (呼び出し先方向のグラフの場合)
+	Callees []*TreeNode `json:"callees,omitempty"`
+	// Cycled は同じ経路上で再び現れたノードの場合にtrueとなる
+	Cycled bool `json:"cycled,omitempty"`
+	// Main はmainパッケージかどうかを表す
+	Main bool `json:"main,omitempty"`
+	// Vendor は vendor ディレクトリに取り込まれたコピー内の関数である場合に、取り込み元モジュールのパスを表す
+	Vendor string `json:"vendored_by,omitempty"`
+	// Truncated は探索が中断されて呼び出し元を展開できなかった場合にその理由を表す
+	Truncated Truncation `json:"truncated,omitempty"`
+	// External はワークスペース外の関数の場合に stdlib または external を表す
+	External string `json:"external,omitempty"`
+	// Cycle は循環 (強連結成分) に含まれるノードの場合に、その循環の番号を表す
+	Cycle int `json:"cycle,omitempty"`
+	// Count は親ノードとの間の呼び出し箇所の数を表す
+	Count int `json:"count,omitempty"`
+	// Sites は親ノードとの間の呼び出し箇所を表す
+	Sites []string `json:"sites,omitempty"`
+}
+
+// Expand は起点ノードから呼び出し元 (Forward の場合は呼び出し先) を経路ごとに深さ優先で展開し、各ノードを fn に渡します
+// 同じ経路上に既に現れたノードは cycled=true で渡し、それ以上展開しません
+// maxDepth>0 の場合、深さ maxDepth のノードの呼び出し元は展開しません
+func (g *Graph) Expand(maxDepth int, fn func(n *Node, depth int, cycled bool)) {
+	root := g.RootNode()
+	if root == nil {
+		return
+	}
+	next := g.Callers
+	if g.Forward {
+		next = g.Callees
+	}
+	onPath := make(map[string]struct{})
+	var walk func(n *Node, depth int)
+	walk = func(n *Node, depth int) {
+		_, cycled := onPath[n.ID]
+		fn(n, depth, cycled)
+		if cycled || (maxDepth > 0 && depth >= maxDepth) {
+			return
+		}
+		onPath[n.ID] = struct{}{}
+		defer delete(onPath, n.ID)
+		for _, c := range next(n.ID) {
+			walk(c, depth+1)
+		}
+	}
+	walk(root, 0)
+}
+
+// Tree は起点ノードから経路ごとに展開したツリーを返します
+// 循環はノードを複製した上で Cycled を立てて打ち切ります
+func (g *Graph) Tree(maxDepth int) *TreeNode {
+	// 深さ優先の訪問順に、各深さの直近のノードを親として子を追加する
+	var (
+		stack []*TreeNode
+		ids   []string
+		tree  *TreeNode
+	)
+	g.Expand(maxDepth, func(n *Node, depth int, cycled bool) {
+		c := &TreeNode{Name: n.Name, Cycled: cycled, Main: n.Main, Vendor: n.Vendor, Truncated: n.Truncated, External: n.External, Cycle: n.Cycle}
+		stack = append(stack[:depth], c)
+		ids = append(ids[:depth], n.ID)
+		if depth == 0 {
+			tree = c
+			return
+		}
+		parent := stack[depth-1]
+		caller, callee := n.ID, ids[depth-1]
+		if g.Forward {
+			caller, callee = callee, caller
+		}
+		for _, s := range g.Sites(caller, callee) {
+			c.Sites = append(c.Sites, s.String())
+		}
+		c.Count = len(c.Sites)
+		if g.Forward {
+			parent.Callees = append(parent.Callees, c)
+			return
+		}
+		parent.Callers = append(parent.Callers, c)
+	})
+	return tree
+}
@@ -1,37 +1,126 @@
 package grep
 
 import (
-	"bufio"
+	"bytes"
 	"context"
+	"io"
 	"io/fs"
-	"os"
 	"path/filepath"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/meian/rev-callgraph/internal/contextutil"
+	"github.com/meian/rev-callgraph/internal/diag"
+	"github.com/meian/rev-callgraph/internal/index"
+	"github.com/meian/rev-callgraph/internal/parallel"
 	"github.com/meian/rev-callgraph/internal/progress"
+	"github.com/meian/rev-callgraph/internal/srcfile"
+	"github.com/meian/rev-callgraph/internal/stats"
 )
 
 // SearchFiles は root 以下の .go ファイルを走査し、
-// target 文字列を含むファイルのパス一覧を返します。
-// メソッド指定の場合 '#' と '.' の両方で検索します。
-func SearchFiles(ctx context.Context, root, target string) ([]string, error) {
-	// 検索パターンを準備
-	patterns := []string{target}
-	// メソッドの場合は#を.に置換
-	if strings.Contains(target, "#") {
-		dot := strings.ReplaceAll(target, "#", ".")
-		patterns = append(patterns, dot)
+// ident を識別子として含むファイルのパス一覧を返します。
+// 前後が識別子を構成する文字の出現 (例: ident が Target の場合の OtherTarget, Target2) は含みません。
+// コンテキストに index.Index が設定されていて root を含む場合は、走査せずにインデックスを参照します。
+func SearchFiles(ctx context.Context, root, ident string) ([]string, error) {
+	defer stats.Start(ctx, stats.PhaseSearch, ident)()
+	// インデックスが利用できる場合は識別子の出現するファイルを引く
+	if idx := index.FromContext(ctx); idx != nil && idx.Covers(root) {
+		files := idx.Files(root, ident)
+		progress.Msgf(ctx, "search files for %s from index: %d files", ident, len(files))
+		stats.Add(ctx, stats.FilesMatched, len(files))
+		return files, nil
 	}
-	// 関数名のみ (例: Target)
-	base := target
-	if i := strings.LastIndexAny(target, ".#"); i >= 0 {
-		base = target[i+1:]
+	return searchFiles(ctx, root, []pattern{{text: []byte(ident), ident: true}})
+}
+
+// SearchImports は root 以下の .go ファイルを走査し、
+// pkg をインポートしている可能性のあるファイルのパス一覧を返します。
+func SearchImports(ctx context.Context, root, pkg string) ([]string, error) {
+	defer stats.Start(ctx, stats.PhaseSearch, pkg)()
+	return searchFiles(ctx, root, []pattern{{text: []byte(`"` + pkg + `"`)}})
+}
+
+// pattern はファイルの内容から検索するバイト列です
+type pattern struct {
+	text []byte
+	// ident が true の場合、前後が識別子を構成する文字でない出現のみにマッチします
+	ident bool
+}
+
+// String は検索するバイト列を文字列で返します
+func (p pattern) String() string {
+	return string(p.text)
+}
+
+// matchAt は data[i:] に出現した p が、識別子の境界の条件を満たすかを判定します
+// 前後の文字が data の範囲外の場合は境界として扱います
+func (p pattern) matchAt(data []byte, i int) bool {
+	if !p.ident {
+		return true
 	}
-	patterns = append(patterns, base+"(")
+	if i > 0 && isIdentByte(data[i-1]) {
+		return false
+	}
+	end := i + len(p.text)
+	return end >= len(data) || !isIdentByte(data[end])
+}
+
+// isIdentByte は b が識別子を構成しうるバイトかを判定します
+// 非ASCII文字は識別子の一部とみなします (Go のソース上で識別子に隣接する非ASCII文字は識別子の一部に限られるため)
+func isIdentByte(b byte) bool {
+	return b == '_' || '0' <= b && b <= '9' || 'a' <= b && b <= 'z' || 'A' <= b && b <= 'Z' || b >= utf8.RuneSelf
+}
 
+// searchFiles は root 以下の .go ファイルのうち、patterns のいずれかを含むファイルのパス一覧を返します。
+// ファイルの読み込みと照合はコンテキストに設定された並列数で行い、結果は走査順に並びます。
+func searchFiles(ctx context.Context, root string, patterns []pattern) ([]string, error) {
 	progress.Msgf(ctx, "search files for %v", patterns)
 
+	candidates, err := walkGoFiles(ctx, root)
+	if err != nil {
+		return nil, err
+	}
+
+	stats.Add(ctx, stats.FilesWalked, len(candidates))
+
+	matched, errs, err := parallel.Map(ctx, candidates, func(path string) (bool, error) {
+		return matchFile(ctx, path, patterns)
+	})
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for i, path := range candidates {
+		if errs[i] != nil {
+			// 読み込めないファイルは診断を出力して候補から除外する
+			diag.Warnf(ctx, path, "検索をスキップしました: %v", errs[i])
+			continue
+		}
+		if matched[i] {
+			files = append(files, path)
+		}
+	}
+	for _, f := range files {
+		progress.Msgf(ctx, "  detect file: %s", f)
+	}
+	stats.Add(ctx, stats.FilesMatched, len(files))
+	return files, nil
+}
+
+// GoFiles は root 以下の .go ファイルのパス一覧を走査順に返します。
+// vendor ディレクトリと "." で始まるディレクトリは走査しません。
+func GoFiles(ctx context.Context, root string) ([]string, error) {
+	files, err := walkGoFiles(ctx, root)
+	if err != nil {
+		return nil, err
+	}
+	stats.Add(ctx, stats.FilesWalked, len(files))
+	return files, nil
+}
+
+// walkGoFiles は root 以下の .go ファイルを走査します
+func walkGoFiles(ctx context.Context, root string) ([]string, error) {
 	var files []string
 	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
@@ -53,30 +142,83 @@ func SearchFiles(ctx context.Context, root, target string) ([]string, error) {
 		if filepath.Ext(path) != ".go" {
 			return nil
 		}
+		files = append(files, path)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
 
-		file, err := os.Open(path)
-		if err != nil {
-			return err
+// chunkSize はファイルを読み込む単位です
+const chunkSize = 64 * 1024
+
+// matchFile は path の内容が patterns のいずれかを含むかを判定します
+// ファイルサイズが上限を超える場合は srcfile.ErrTooLarge を返します
+func matchFile(ctx context.Context, path string, patterns []pattern) (bool, error) {
+	file, err := srcfile.Open(ctx, path)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+	return matchReader(file, patterns)
+}
+
+// matchReader は r の内容が patterns のいずれかを含むかを判定します
+// 内容は一定サイズずつ読み込むため行の長さに制限はなく、読み込み単位の境界をまたぐ出現も検出します
+// patterns は改行を含まないため、行ごとに照合した場合と結果は同じです
+func matchReader(r io.Reader, patterns []pattern) (bool, error) {
+	longest := 0
+	for _, p := range patterns {
+		longest = max(longest, len(p.text))
+	}
+	// 境界をまたぐ出現と、その前後1バイトを判定できるよう前回の末尾を残して次の読み込み分と連結する
+	carry := longest + 1
+	buf := make([]byte, carry+chunkSize)
+	keep := 0
+	// atStart は data の先頭がファイルの先頭かどうか
+	atStart := true
+	for {
+		n, err := r.Read(buf[keep:])
+		data := buf[:keep+n]
+		eof := err == io.EOF
+		if err != nil && !eof {
+			return false, err
 		}
-		defer file.Close()
-
-		scanner := bufio.NewScanner(file)
-		for scanner.Scan() {
-			line := scanner.Text()
-			for _, p := range patterns {
-				if strings.Contains(line, p) {
-					files = append(files, path)
-					return nil
-				}
+		for _, p := range patterns {
+			if matchChunk(data, p, atStart, eof) {
+				return true, nil
 			}
 		}
-		return scanner.Err()
-	})
-	if err != nil {
-		return nil, err
+		if eof {
+			return false, nil
+		}
+		keep = min(carry, len(data))
+		atStart = atStart && keep == len(data)
+		copy(buf, data[len(data)-keep:])
 	}
-	for _, f := range files {
-		progress.Msgf(ctx, "  detect file: %s", f)
+}
+
+// matchChunk は data 内に p の出現があるかを判定します
+// atStart が false の場合、data の先頭から始まる出現は前回の読み込みで判定済みのため対象外とします
+// eof が false の場合、後続の文字が未読の末尾の出現は次回の判定に持ち越します
+func matchChunk(data []byte, p pattern, atStart, eof bool) bool {
+	for off := 0; ; {
+		i := bytes.Index(data[off:], p.text)
+		if i < 0 {
+			return false
+		}
+		i += off
+		off = i + 1
+		if !atStart && i == 0 {
+			continue
+		}
+		if !eof && i+len(p.text) >= len(data) {
+			return false
+		}
+		if p.matchAt(data, i) {
+			return true
+		}
 	}
-	return files, nil
 }
@@ -1,13 +1,18 @@
 package grep_test
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/meian/rev-callgraph/internal/diag"
 	"github.com/meian/rev-callgraph/internal/grep"
+	"github.com/meian/rev-callgraph/internal/index"
+	"github.com/meian/rev-callgraph/internal/srcfile"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -59,3 +64,93 @@ func TestSearchFiles_ContextCancellation(t *testing.T) {
 	assert.True(t, errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled),
 		"context.DeadlineExceeded または context.Canceled が期待されますが、実際: %v", err)
 }
+
+func TestSearchFiles_Index(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "pkg"), 0755))
+	file1 := filepath.Join(root, "pkg", "a.go")
+	require.NoError(t, os.WriteFile(file1, []byte(`package pkg
+func a() { targetFunc() }`), 0644))
+	// コメント内の出現はインデックスには含まれない
+	file2 := filepath.Join(root, "pkg", "b.go")
+	require.NoError(t, os.WriteFile(file2, []byte(`package pkg
+// call targetFunc()`), 0644))
+
+	ctx := context.Background()
+	idx, err := index.Build(ctx, root)
+	require.NoError(t, err)
+	ctx = index.WithIndex(ctx, idx)
+
+	files, err := grep.SearchFiles(ctx, filepath.Join(root, "pkg"), "targetFunc")
+	require.NoError(t, err, "SearchFiles error")
+	assert.Equal(t, []string{file1}, files)
+}
+
+func TestSearchFiles_LongLine(t *testing.T) {
+	root := t.TempDir()
+	// 1行が bufio.Scanner の既定上限 (64KiB) を超えるファイル
+	long := filepath.Join(root, "long.go")
+	require.NoError(t, os.WriteFile(long, []byte("package pkg\nvar s = \""+strings.Repeat("x", 200*1024)+"\"; func f() { targetFunc() }\n"), 0644))
+	// 読み込み単位 (64KiB) の境界をまたいでパターンが現れるファイル
+	boundary := filepath.Join(root, "boundary.go")
+	require.NoError(t, os.WriteFile(boundary, []byte(strings.Repeat("/", 64*1024+5)+"targetFunc()\n"), 0644))
+	// マッチしない長い行のファイル
+	nomatch := filepath.Join(root, "nomatch.go")
+	require.NoError(t, os.WriteFile(nomatch, []byte(strings.Repeat("y", 300*1024)), 0644))
+
+	files, err := grep.SearchFiles(context.Background(), root, "targetFunc")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{long, boundary}, files)
+}
+
+func TestSearchFiles_MaxFileSize(t *testing.T) {
+	root := t.TempDir()
+	small := filepath.Join(root, "small.go")
+	require.NoError(t, os.WriteFile(small, []byte("package pkg\nfunc f() { targetFunc() }\n"), 0644))
+	large := filepath.Join(root, "large.go")
+	require.NoError(t, os.WriteFile(large, []byte("package pkg\nfunc g() { targetFunc() }\n"+strings.Repeat("// padding\n", 1024)), 0644))
+
+	var out bytes.Buffer
+	ctx := diag.WithReporter(context.Background(), diag.NewReporter(&out))
+	ctx = srcfile.WithMaxSize(ctx, 1024)
+
+	// 上限を超えるファイルは警告を出してスキップし、検索は続行する
+	files, err := grep.SearchFiles(ctx, root, "targetFunc")
+	require.NoError(t, err)
+	assert.Equal(t, []string{small}, files)
+	assert.Contains(t, out.String(), large)
+
+	// インデックス構築時も同様にスキップする
+	out.Reset()
+	idx, err := index.Build(ctx, root)
+	require.NoError(t, err)
+	files, err = grep.SearchFiles(index.WithIndex(ctx, idx), root, "targetFunc")
+	require.NoError(t, err)
+	assert.Equal(t, []string{small}, files)
+	assert.Contains(t, out.String(), large)
+}
+
+func TestSearchFiles_IdentBoundary(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"other.go":    "package pkg\nfunc a() { OtherTarget() }\n",
+		"suffix.go":   "package pkg\nfunc a() { Target2(); MyTarget_() }\n",
+		"space.go":    "package pkg\nfunc a() { foo.Target () }\n",
+		"selector.go": "package pkg\nfunc a() { foo.\n\tTarget() }\n",
+		"value.go":    "package pkg\nvar f = x.Target\n",
+		"unicode.go":  "package pkg\nfunc a() { ÄTarget() }\n",
+		"head.go":     "Target",
+	}
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(root, name), []byte(content), 0644))
+	}
+
+	got, err := grep.SearchFiles(context.Background(), root, "Target")
+	require.NoError(t, err)
+	var names []string
+	for _, f := range got {
+		names = append(names, filepath.Base(f))
+	}
+	// 識別子の前後が識別子を構成する文字の出現は除外し、空白や改行を挟むセレクタは含む
+	assert.Equal(t, []string{"head.go", "selector.go", "space.go", "value.go"}, names)
+}
@@ -0,0 +1,19 @@
+package index
+
+import (
+	"context"
+)
+
+type indexKey struct{}
+
+// WithIndex はコンテキストに Index を追加します。
+func WithIndex(ctx context.Context, idx *Index) context.Context {
+	return context.WithValue(ctx, indexKey{}, idx)
+}
+
+// FromContext はコンテキストに紐づけられた Index を返します。
+// Index が存在しない場合は nil を返します。
+func FromContext(ctx context.Context) *Index {
+	idx, _ := ctx.Value(indexKey{}).(*Index)
+	return idx
+}
@@ -0,0 +1,205 @@
+package index
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/meian/rev-callgraph/internal/git"
+	"github.com/meian/rev-callgraph/internal/progress"
+	"github.com/meian/rev-callgraph/internal/srcfile"
+)
+
+// snapshotVersion は保存するインデックスの形式バージョンです
+// snapshot や Occurrence の構造を変更した場合は更新して既存のインデックスを無効化します
+const snapshotVersion = 1
+
+// snapshot はディスクに保存するインデックスです
+type snapshot struct {
+	Version int
+	Root    string
+	// Commit はインデックスを構築した時点の HEAD のコミット
+	Commit string
+	// Dirty は構築時に Commit から変更されていた (コミットされていない) ファイル
+	Dirty []string
+	// MaxSize は構築時のファイルサイズの上限
+	MaxSize int64
+	Files   []string
+	Occ     map[string][]Occurrence
+}
+
+// Incremental は root を含む git リポジトリのコミットを記録したインデックスを dir に保存し、
+// 以降の呼び出しでは保存時のコミットから変更されたファイルのみ再抽出してインデックスを返します
+// 保存したコミットが HEAD の祖先でない (履歴が分岐した) 場合や、保存したインデックスが
+// 使えない場合は全体を構築し直します
+// root が git リポジトリでない場合は保存せずに Build と同様に構築します
+func Incremental(ctx context.Context, root, dir string) (*Index, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	head, err := git.Head(ctx, root)
+	if err != nil {
+		progress.Msgf(ctx, "incremental index disabled: %v", err)
+		return Build(ctx, root)
+	}
+
+	path := snapshotPath(dir, root)
+	idx, err := refresh(ctx, root, head, readSnapshot(path))
+	if err != nil {
+		return nil, err
+	}
+
+	// 次回の差分に含めるため、HEAD から変更されているファイルを記録する
+	dirty, err := git.ChangedFiles(ctx, root, head)
+	if err != nil {
+		progress.Msgf(ctx, "index not saved: %v", err)
+		return idx, nil
+	}
+	s := &snapshot{
+		Version: snapshotVersion,
+		Root:    root,
+		Commit:  head,
+		Dirty:   idx.filter(dirty),
+		MaxSize: srcfile.MaxSize(ctx),
+		Files:   idx.files,
+		Occ:     idx.occ,
+	}
+	if err := writeSnapshot(path, s); err != nil {
+		// 保存に失敗してもインデックスは利用できるため続行する
+		progress.Msgf(ctx, "index not saved: %v", err)
+	}
+	return idx, nil
+}
+
+// refresh は保存したインデックス s を head の作業ツリーに合わせて更新します
+// s が使えない場合は全体を構築します
+func refresh(ctx context.Context, root, head string, s *snapshot) (*Index, error) {
+	if s == nil || s.Version != snapshotVersion || s.Root != root || s.MaxSize != srcfile.MaxSize(ctx) {
+		return Build(ctx, root)
+	}
+	ok, err := git.IsAncestor(ctx, root, s.Commit, head)
+	if err != nil || !ok {
+		progress.Msgf(ctx, "history diverged from %s, rebuild index", short(s.Commit))
+		return Build(ctx, root)
+	}
+	changed, err := git.ChangedFiles(ctx, root, s.Commit)
+	if err != nil {
+		progress.Msgf(ctx, "rebuild index: %v", err)
+		return Build(ctx, root)
+	}
+
+	idx := &Index{root: root, files: s.Files, occ: s.Occ}
+	if idx.occ == nil {
+		idx.occ = make(map[string][]Occurrence)
+	}
+	changed = idx.filter(append(changed, s.Dirty...))
+	progress.Msgf(ctx, "update identifier index in %s: %d files changed since %s", root, len(changed), short(s.Commit))
+	if err := idx.update(ctx, changed); err != nil {
+		return nil, err
+	}
+	progress.Msgf(ctx, "  indexed %d files, %d identifiers", len(idx.files), len(idx.occ))
+	return idx, nil
+}
+
+// filter は files のうちインデックスの対象となる .go ファイルを重複なく辞書順で返します
+func (idx *Index) filter(files []string) []string {
+	var result []string
+	for _, f := range files {
+		if filepath.Ext(f) == ".go" && idx.Covers(filepath.Dir(f)) {
+			result = append(result, f)
+		}
+	}
+	slices.Sort(result)
+	return slices.Compact(result)
+}
+
+// update は files の出現位置を破棄し、存在するファイルのみ読み込み直して登録します
+func (idx *Index) update(ctx context.Context, files []string) error {
+	if len(files) == 0 {
+		return nil
+	}
+	drop := make(map[string]struct{}, len(files))
+	for _, f := range files {
+		drop[f] = struct{}{}
+	}
+	idx.files = slices.DeleteFunc(idx.files, func(f string) bool {
+		_, ok := drop[f]
+		return ok
+	})
+	for name, occ := range idx.occ {
+		occ = slices.DeleteFunc(occ, func(o Occurrence) bool {
+			_, ok := drop[o.File]
+			return ok
+		})
+		if len(occ) == 0 {
+			delete(idx.occ, name)
+			continue
+		}
+		idx.occ[name] = occ
+	}
+
+	// 削除されたファイルは除外したままにする
+	var exists []string
+	for _, f := range files {
+		if fi, err := os.Stat(f); err == nil && fi.Mode().IsRegular() {
+			exists = append(exists, f)
+		}
+	}
+	return idx.add(ctx, exists)
+}
+
+// snapshotPath は root のインデックスを保存するファイルパスを返します
+func snapshotPath(dir, root string) string {
+	sum := sha256.Sum256([]byte(root))
+	return filepath.Join(dir, hex.EncodeToString(sum[:])+".gob")
+}
+
+// readSnapshot は保存したインデックスを読み込みます
+// 存在しない、または壊れている場合は nil を返します
+func readSnapshot(path string) *snapshot {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	var s snapshot
+	if err := gob.NewDecoder(f).Decode(&s); err != nil {
+		return nil
+	}
+	return &s
+}
+
+// writeSnapshot はインデックスを保存します
+// 書き込み途中のファイルを読まないよう一時ファイルに書いてからリネームします
+func writeSnapshot(path string, s *snapshot) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if err := gob.NewEncoder(tmp).Encode(s); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// short は表示用に短縮したコミットハッシュを返します
+func short(commit string) string {
+	if len(commit) > 12 {
+		return commit[:12]
+	}
+	return commit
+}
@@ -0,0 +1,111 @@
+package index_test
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/meian/rev-callgraph/internal/index"
+	"github.com/meian/rev-callgraph/internal/stats"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// gitRun は dir で git コマンドを実行します
+func gitRun(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+		"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com")
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, "git %v: %s", args, out)
+}
+
+// incremental は読み込んだファイル数を計測しながら index.Incremental を実行します
+func incremental(t *testing.T, root, dir string) (*index.Index, int64) {
+	t.Helper()
+	rec := stats.NewRecorder()
+	idx, err := index.Incremental(stats.WithRecorder(context.Background(), rec), root, dir)
+	require.NoError(t, err)
+	return idx, rec.Count(stats.FilesWalked)
+}
+
+func TestIncremental(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git が見つかりません")
+	}
+	root := t.TempDir()
+	dir := t.TempDir()
+	fileA := filepath.Join(root, "a.go")
+	fileB := filepath.Join(root, "b.go")
+	require.NoError(t, os.WriteFile(fileA, []byte("package p\nfunc A() { Target() }\n"), 0644))
+	require.NoError(t, os.WriteFile(fileB, []byte("package p\nfunc B() {}\n"), 0644))
+	gitRun(t, root, "init", "-q")
+	gitRun(t, root, "add", ".")
+	gitRun(t, root, "commit", "-q", "-m", "init")
+
+	// 初回は全体を構築する
+	idx, walked := incremental(t, root, dir)
+	assert.Equal(t, int64(2), walked)
+	assert.Equal(t, []string{fileA}, idx.Files(root, "Target"))
+
+	// コミットされた変更と、コミットされていない変更・追加・削除のみ再抽出する
+	require.NoError(t, os.WriteFile(fileB, []byte("package p\nfunc B() { Target() }\n"), 0644))
+	gitRun(t, root, "commit", "-q", "-am", "b")
+	fileC := filepath.Join(root, "c.go")
+	require.NoError(t, os.WriteFile(fileC, []byte("package p\nfunc C() { Target() }\n"), 0644))
+	require.NoError(t, os.Remove(fileA))
+	idx, walked = incremental(t, root, dir)
+	assert.Equal(t, int64(2), walked, "b.go と c.go のみ読み込む")
+	assert.Equal(t, []string{fileB, fileC}, idx.Files(root, "Target"))
+	assert.Equal(t, 2, idx.Len())
+
+	// 前回コミットされていなかったファイルは元に戻された場合も再抽出する
+	gitRun(t, root, "checkout", "-q", "--", "a.go")
+	require.NoError(t, os.Remove(fileC))
+	idx, walked = incremental(t, root, dir)
+	assert.Equal(t, int64(1), walked, "a.go のみ読み込む")
+	assert.Equal(t, []string{fileA, fileB}, idx.Files(root, "Target"))
+
+	// 変更がなければ読み込まない
+	_, walked = incremental(t, root, dir)
+	assert.Equal(t, int64(0), walked)
+
+	// 名前を変更したファイルは変更前のパスの出現位置を破棄する
+	fileD := filepath.Join(root, "d.go")
+	gitRun(t, root, "mv", "a.go", "d.go")
+	gitRun(t, root, "commit", "-q", "-m", "rename")
+	idx, walked = incremental(t, root, dir)
+	assert.Equal(t, int64(1), walked, "d.go のみ読み込む")
+	assert.Equal(t, []string{fileB, fileD}, idx.Files(root, "Target"))
+	assert.Equal(t, 2, idx.Len())
+	gitRun(t, root, "mv", "d.go", "a.go")
+	gitRun(t, root, "commit", "-q", "-m", "rename back")
+	idx, _ = incremental(t, root, dir)
+	assert.Equal(t, []string{fileA, fileB}, idx.Files(root, "Target"))
+
+	// 履歴が分岐した場合は全体を構築し直す
+	require.NoError(t, os.WriteFile(fileB, []byte("package p\nfunc B() { Other() }\n"), 0644))
+	gitRun(t, root, "commit", "-q", "--amend", "-am", "b2")
+	idx, walked = incremental(t, root, dir)
+	assert.Equal(t, int64(2), walked)
+	assert.Equal(t, []string{fileA}, idx.Files(root, "Target"))
+}
+
+func TestIncremental_NotRepository(t *testing.T) {
+	root := t.TempDir()
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "a.go"), []byte("package p\nfunc A() { Target() }\n"), 0644))
+
+	// git リポジトリでない場合は保存せずに全体を構築する
+	idx, walked := incremental(t, root, dir)
+	assert.Equal(t, int64(1), walked)
+	assert.Equal(t, 1, idx.Len())
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
@@ -0,0 +1,232 @@
+// Package index はワークスペース内の識別子の出現位置を索引する転置インデックスを提供します
+package index
+
+import (
+	"context"
+	"go/scanner"
+	"go/token"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/meian/rev-callgraph/internal/contextutil"
+	"github.com/meian/rev-callgraph/internal/diag"
+	"github.com/meian/rev-callgraph/internal/parallel"
+	"github.com/meian/rev-callgraph/internal/progress"
+	"github.com/meian/rev-callgraph/internal/srcfile"
+	"github.com/meian/rev-callgraph/internal/stats"
+)
+
+// Occurrence は識別子の出現位置を表します
+type Occurrence struct {
+	// File はファイルパス
+	File string
+	// Offset はファイル先頭からのバイトオフセット
+	Offset int
+	// Line は行番号 (1始まり)
+	Line int
+	// Column は列番号 (1始まり, バイト単位)
+	Column int
+}
+
+// Index は識別子およびセレクタの組 (pkg.Name) からファイルと出現位置を引く転置インデックスです
+// コメントや文字列リテラル内の出現は含みません
+type Index struct {
+	root  string
+	files []string
+	occ   map[string][]Occurrence
+}
+
+// Build は root 以下の .go ファイルを一度だけ走査してインデックスを構築します
+// grep.SearchFiles と同様に vendor ディレクトリと "." で始まるディレクトリはスキップします
+func Build(ctx context.Context, root string) (*Index, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	progress.Msgf(ctx, "build identifier index in %s", root)
+	idx := &Index{root: root, occ: make(map[string][]Occurrence)}
+	var files []string
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if contextutil.IsCanceledOrTimedOut(ctx) {
+			return ctx.Err()
+		}
+		if d.IsDir() {
+			if path != root && skipDir(d.Name()) {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if filepath.Ext(path) != ".go" {
+			return nil
+		}
+		files = append(files, path)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := idx.add(ctx, files); err != nil {
+		return nil, err
+	}
+	progress.Msgf(ctx, "  indexed %d files, %d identifiers", len(idx.files), len(idx.occ))
+	return idx, nil
+}
+
+// add は files を読み込んで字句解析し、インデックスに登録します
+// 読み込めないファイルは診断を出力して除外します
+func (idx *Index) add(ctx context.Context, files []string) error {
+	stats.Add(ctx, stats.FilesWalked, len(files))
+
+	// 読み込みと字句解析は並列に行い、登録は走査順に行う
+	tokens, errs, err := parallel.Map(ctx, files, func(path string) ([]ident, error) {
+		src, err := srcfile.ReadFile(ctx, path)
+		if err != nil {
+			return nil, err
+		}
+		return scan(path, src), nil
+	})
+	if err != nil {
+		return err
+	}
+	added := make(map[string]struct{})
+	for i, path := range files {
+		if errs[i] != nil {
+			// 読み込めないファイルは診断を出力してインデックスから除外する
+			diag.Warnf(ctx, path, "インデックスから除外しました: %v", errs[i])
+			continue
+		}
+		idx.files = append(idx.files, path)
+		for _, t := range tokens[i] {
+			idx.occ[t.name] = append(idx.occ[t.name], t.occ)
+			added[t.name] = struct{}{}
+		}
+	}
+	// ファイル単位の検索を二分探索で行えるようファイルパス順に並べる
+	for name := range added {
+		slices.SortStableFunc(idx.occ[name], func(a, b Occurrence) int {
+			return strings.Compare(a.File, b.File)
+		})
+	}
+	return nil
+}
+
+// skipDir はインデックス対象外とするディレクトリ名かどうかを判定します
+func skipDir(name string) bool {
+	return name == "vendor" || strings.HasPrefix(name, ".")
+}
+
+// ident は字句解析で得られた識別子またはセレクタの組とその出現位置です
+type ident struct {
+	name string
+	occ  Occurrence
+}
+
+// scan はファイルをトークンに分解し、識別子とセレクタの組の出現位置を返します
+func scan(path string, src []byte) []ident {
+	fset := token.NewFileSet()
+	file := fset.AddFile(path, -1, len(src))
+	var s scanner.Scanner
+	s.Init(file, src, nil, 0)
+
+	var (
+		result    []ident
+		prevIdent string
+		prevPos   token.Pos
+		afterDot  bool
+	)
+	record := func(name string, pos token.Pos) {
+		p := fset.Position(pos)
+		result = append(result, ident{name: name, occ: Occurrence{
+			File:   p.Filename,
+			Offset: p.Offset,
+			Line:   p.Line,
+			Column: p.Column,
+		}})
+	}
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		switch tok {
+		case token.IDENT:
+			record(lit, pos)
+			if afterDot && prevIdent != "" {
+				record(prevIdent+"."+lit, prevPos)
+			}
+			prevIdent, prevPos, afterDot = lit, pos, false
+		case token.PERIOD:
+			afterDot = prevIdent != ""
+		default:
+			prevIdent, afterDot = "", false
+		}
+	}
+	return result
+}
+
+// Covers は dir 以下のファイルがインデックスに含まれているかを判定します
+// インデックスのルート外や、スキップ対象のディレクトリ配下は含まれません
+func (idx *Index) Covers(dir string) bool {
+	rel, err := filepath.Rel(idx.root, dir)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return false
+	}
+	if rel == "." {
+		return true
+	}
+	for _, name := range strings.Split(rel, string(filepath.Separator)) {
+		if skipDir(name) {
+			return false
+		}
+	}
+	return true
+}
+
+// Occurrences は name (識別子または "X.Sel" 形式のセレクタ) の全出現位置を返します
+func (idx *Index) Occurrences(name string) []Occurrence {
+	return idx.occ[name]
+}
+
+// InFile は file 内での name の出現位置を返します
+func (idx *Index) InFile(file, name string) []Occurrence {
+	occ := idx.occ[name]
+	start, _ := slices.BinarySearchFunc(occ, file, func(o Occurrence, f string) int {
+		return strings.Compare(o.File, f)
+	})
+	end := start
+	for end < len(occ) && occ[end].File == file {
+		end++
+	}
+	return occ[start:end]
+}
+
+// Files は root 以下で names のいずれかが出現するファイルのパス一覧を辞書順で返します
+func (idx *Index) Files(root string, names ...string) []string {
+	seen := make(map[string]struct{})
+	var files []string
+	for _, name := range names {
+		for _, o := range idx.occ[name] {
+			if _, ok := seen[o.File]; ok {
+				continue
+			}
+			if rel, err := filepath.Rel(root, o.File); err != nil || strings.HasPrefix(rel, "..") {
+				continue
+			}
+			seen[o.File] = struct{}{}
+			files = append(files, o.File)
+		}
+	}
+	slices.Sort(files)
+	return files
+}
+
+// Len はインデックス済みのファイル数を返します
+func (idx *Index) Len() int {
+	return len(idx.files)
+}
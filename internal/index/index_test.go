@@ -0,0 +1,67 @@
+package index_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/meian/rev-callgraph/internal/index"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuild(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "pkg"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "vendor", "pkg"), 0755))
+	fileA := filepath.Join(root, "pkg", "a.go")
+	require.NoError(t, os.WriteFile(fileA, []byte(`package pkg
+
+import "example.com/foo"
+
+func A() {
+	foo.Target()
+	foo .
+		Target()
+}
+`), 0644))
+	fileB := filepath.Join(root, "pkg", "b.go")
+	require.NoError(t, os.WriteFile(fileB, []byte(`package pkg
+
+// Target はコメント内なので索引しない
+var s = "Target"
+`), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "vendor", "pkg", "c.go"), []byte(`package pkg
+
+func Target() {}
+`), 0644))
+
+	idx, err := index.Build(context.Background(), root)
+	require.NoError(t, err)
+	assert.Equal(t, 2, idx.Len(), "vendor 配下は索引しない")
+
+	assert.Equal(t, []string{fileA}, idx.Files(root, "Target"))
+	occ := idx.InFile(fileA, "foo.Target")
+	require.Len(t, occ, 2, "改行や空白を挟んだセレクタも索引する")
+	assert.Equal(t, 6, occ[0].Line)
+	assert.Equal(t, 2, occ[0].Column)
+	assert.Empty(t, idx.InFile(fileB, "Target"))
+
+	assert.True(t, idx.Covers(filepath.Join(root, "pkg")))
+	assert.False(t, idx.Covers(filepath.Join(root, "vendor", "pkg")))
+	assert.False(t, idx.Covers(filepath.Dir(root)))
+}
+
+func TestBuild_ContextCancellation(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "a.go"), []byte("package a\n"), 0644))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := index.Build(ctx, root)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled),
+		"context.DeadlineExceeded または context.Canceled が期待されますが、実際: %v", err)
+}
@@ -0,0 +1,78 @@
+// Package parallel はコンテキストで共有する上限付きの並列実行を提供します
+package parallel
+
+import (
+	"context"
+	"sync"
+
+	"github.com/meian/rev-callgraph/internal/contextutil"
+)
+
+// limiter は同時に実行する追加ゴルーチン数の上限を管理します
+type limiter struct {
+	sem chan struct{}
+}
+
+type limiterKey struct{}
+
+// WithJobs はコンテキストに並列数 jobs を設定します。
+// jobs が1以下の場合は逐次実行になります。
+// 入れ子の Map でも同じ上限を共有するため、全体の並列数は jobs を超えません。
+func WithJobs(ctx context.Context, jobs int) context.Context {
+	if jobs <= 1 {
+		return context.WithValue(ctx, limiterKey{}, (*limiter)(nil))
+	}
+	// 呼び出し元のゴルーチン自身も処理を行うため、追加で起動するのは jobs-1 個まで
+	return context.WithValue(ctx, limiterKey{}, &limiter{sem: make(chan struct{}, jobs-1)})
+}
+
+// Jobs はコンテキストに設定された並列数を返します。
+func Jobs(ctx context.Context) int {
+	l, _ := ctx.Value(limiterKey{}).(*limiter)
+	if l == nil {
+		return 1
+	}
+	return cap(l.sem) + 1
+}
+
+// Map は items の各要素に fn を適用し、結果を items と同じ順序で返します。
+// 空きがあれば別ゴルーチンで、なければ呼び出し元のゴルーチンで実行するため、
+// 入れ子で呼び出してもデッドロックしません。
+// コンテキストがキャンセルされた場合は未着手の要素を処理せず ctx.Err() を返します。
+// その場合も処理済みの要素の結果は返し、未着手の要素の errs には ctx.Err() を設定します。
+func Map[T, R any](ctx context.Context, items []T, fn func(T) (R, error)) ([]R, []error, error) {
+	results := make([]R, len(items))
+	errs := make([]error, len(items))
+	l, _ := ctx.Value(limiterKey{}).(*limiter)
+
+	var wg sync.WaitGroup
+	for i, item := range items {
+		if contextutil.IsCanceledOrTimedOut(ctx) {
+			for j := i; j < len(items); j++ {
+				errs[j] = ctx.Err()
+			}
+			break
+		}
+		if l != nil {
+			select {
+			case l.sem <- struct{}{}:
+				wg.Add(1)
+				go func() {
+					defer func() {
+						<-l.sem
+						wg.Done()
+					}()
+					results[i], errs[i] = fn(item)
+				}()
+				continue
+			default:
+			}
+		}
+		results[i], errs[i] = fn(item)
+	}
+	wg.Wait()
+	if contextutil.IsCanceledOrTimedOut(ctx) {
+		return results, errs, ctx.Err()
+	}
+	return results, errs, nil
+}
@@ -0,0 +1,93 @@
+package parallel_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/meian/rev-callgraph/internal/parallel"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMap_Order(t *testing.T) {
+	ctx := parallel.WithJobs(context.Background(), 4)
+	items := make([]int, 100)
+	for i := range items {
+		items[i] = i
+	}
+	errFail := errors.New("fail")
+
+	results, errs, err := parallel.Map(ctx, items, func(v int) (int, error) {
+		if v%10 == 0 {
+			return 0, errFail
+		}
+		return v * 2, nil
+	})
+	require.NoError(t, err)
+	for i, v := range items {
+		if v%10 == 0 {
+			assert.ErrorIs(t, errs[i], errFail)
+			continue
+		}
+		assert.NoError(t, errs[i])
+		assert.Equal(t, v*2, results[i])
+	}
+}
+
+func TestMap_Nested(t *testing.T) {
+	ctx := parallel.WithJobs(context.Background(), 2)
+	var running, peak atomic.Int64
+	track := func() func() {
+		n := running.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		return func() { running.Add(-1) }
+	}
+
+	results, _, err := parallel.Map(ctx, []int{1, 2, 3, 4}, func(v int) (int, error) {
+		inner, _, err := parallel.Map(ctx, []int{1, 2, 3}, func(w int) (int, error) {
+			defer track()()
+			return v * w, nil
+		})
+		if err != nil {
+			return 0, err
+		}
+		sum := 0
+		for _, r := range inner {
+			sum += r
+		}
+		return sum, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []int{6, 12, 18, 24}, results)
+	assert.LessOrEqual(t, peak.Load(), int64(2), "並列数の上限を超えてはいけません")
+}
+
+func TestMap_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(parallel.WithJobs(context.Background(), 1))
+	var calls int
+	results, errs, err := parallel.Map(ctx, []int{1, 2, 3}, func(v int) (int, error) {
+		calls++
+		cancel()
+		return v, nil
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 1, calls, "キャンセル後の要素は処理してはいけません")
+	// 処理済みの結果は保持し、未処理の要素にはキャンセルのエラーを設定する
+	assert.Equal(t, []int{1, 0, 0}, results)
+	assert.NoError(t, errs[0])
+	assert.ErrorIs(t, errs[1], context.Canceled)
+	assert.ErrorIs(t, errs[2], context.Canceled)
+}
+
+func TestJobs(t *testing.T) {
+	assert.Equal(t, 1, parallel.Jobs(context.Background()))
+	assert.Equal(t, 1, parallel.Jobs(parallel.WithJobs(context.Background(), 0)))
+	assert.Equal(t, 8, parallel.Jobs(parallel.WithJobs(context.Background(), 8)))
+}
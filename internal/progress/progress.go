@@ -3,6 +3,7 @@ package progress
 import (
 	"fmt"
 	"io"
+	"sync"
 	"time"
 
 	"github.com/fatih/color"
@@ -16,8 +17,10 @@ var (
 )
 
 // Messenger は進行過程のメッセージを出力するための構造体です。
+// 複数のゴルーチンから同時に呼び出しても出力が混ざらないよう排他制御します。
 type Messenger struct {
-	w io.Writer
+	mu sync.Mutex
+	w  io.Writer
 }
 
 func NewMessenger(w io.Writer) *Messenger {
@@ -30,6 +33,8 @@ func (m *Messenger) Msg(msg string) {
 		return
 	}
 	msg = fmt.Sprintf("[%s]%s", time.Now().Format("15:04:05"), msg)
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	if msg[len(msg)-1] != '\n' {
 		println(m.w, msg)
 		return
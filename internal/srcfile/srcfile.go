@@ -0,0 +1,76 @@
+// Package srcfile はサイズの上限を考慮してソースファイルを開く関数を提供します
+package srcfile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// ErrTooLarge はファイルサイズが上限を超えていることを表します
+var ErrTooLarge = errors.New("ファイルサイズが上限を超えています")
+
+type maxSizeKey struct{}
+
+// WithMaxSize はコンテキストに読み込むファイルサイズの上限を設定します。
+// size が0以下の場合は上限なしになります。
+func WithMaxSize(ctx context.Context, size int64) context.Context {
+	return context.WithValue(ctx, maxSizeKey{}, size)
+}
+
+// MaxSize はコンテキストに設定されたファイルサイズの上限を返します。
+// 設定されていない場合は0 (上限なし) を返します。
+func MaxSize(ctx context.Context) int64 {
+	size, _ := ctx.Value(maxSizeKey{}).(int64)
+	return size
+}
+
+// Check は path のファイルサイズが上限以下かを確認します。
+// 上限を超えている場合は ErrTooLarge をラップしたエラーを返します。
+func Check(ctx context.Context, path string) error {
+	limit := MaxSize(ctx)
+	if limit <= 0 {
+		return nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	return checkSize(info.Size(), limit)
+}
+
+// Open は上限を確認した上で path のファイルを開きます。
+func Open(ctx context.Context, path string) (*os.File, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	if limit := MaxSize(ctx); limit > 0 {
+		info, err := f.Stat()
+		if err == nil {
+			err = checkSize(info.Size(), limit)
+		}
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// ReadFile は上限を確認した上で path のファイルの内容を返します。
+func ReadFile(ctx context.Context, path string) ([]byte, error) {
+	if err := Check(ctx, path); err != nil {
+		return nil, err
+	}
+	return os.ReadFile(path)
+}
+
+// checkSize は size が limit を超えていれば ErrTooLarge をラップしたエラーを返します。
+func checkSize(size, limit int64) error {
+	if size > limit {
+		return fmt.Errorf("%w (%d > %d バイト)", ErrTooLarge, size, limit)
+	}
+	return nil
+}
@@ -0,0 +1,256 @@
+// Package stats は処理フェーズごとの所要時間と件数を計測します
+package stats
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Counter は計測する件数の種類を表します
+type Counter int
+
+const (
+	// FilesWalked は走査した .go ファイル数
+	FilesWalked Counter = iota
+	// FilesMatched は検索で候補となったファイル数
+	FilesMatched
+	// FilesParsed は解析 (キャッシュミスを含む) したファイル数
+	FilesParsed
+	// CacheHits は解析キャッシュにヒットしたファイル数
+	CacheHits
+	// PackagesDefined は定義を索引したパッケージ数
+	PackagesDefined
+	// Nodes は出力するグラフのノード数
+	Nodes
+	// Edges は出力するグラフのエッジ数
+	Edges
+
+	numCounters
+)
+
+// counterNames は出力時の件数の名前です
+var counterNames = [numCounters]string{
+	FilesWalked:     "files walked",
+	FilesMatched:    "files matched",
+	FilesParsed:     "files parsed",
+	CacheHits:       "cache hits",
+	PackagesDefined: "packages defined",
+	Nodes:           "nodes",
+	Edges:           "edges",
+}
+
+// フェーズ名
+const (
+	// PhaseScan はモジュールの検出
+	PhaseScan = "scan"
+	// PhaseIndex は識別子インデックスの構築
+	PhaseIndex = "index"
+	// PhaseSearch はファイルの絞り込み
+	PhaseSearch = "search"
+	// PhaseParse はファイルの解析と呼び出しの照合
+	PhaseParse = "parse"
+	// PhaseDefinition は関数/メソッド定義の検索
+	PhaseDefinition = "definition"
+	// PhaseTarget は1つの関数 (パッケージ) の呼び出し元の探索
+	PhaseTarget = "target"
+	// PhaseGraph はグラフ全体の構築
+	PhaseGraph = "graph"
+	// PhasePrint は出力
+	PhasePrint = "print"
+)
+
+// span は計測した区間です
+type span struct {
+	phase string
+	name  string
+	start time.Duration
+	end   time.Duration
+	lane  int
+}
+
+// Recorder は件数と区間を記録します
+// 複数のゴルーチンから同時に呼び出せます
+type Recorder struct {
+	begin    time.Time
+	counters [numCounters]atomic.Int64
+
+	mu    sync.Mutex
+	spans []span
+	// lanes はトレース上で区間を配置する行の使用状況
+	lanes []bool
+}
+
+// NewRecorder は現在時刻を起点とする Recorder を返します
+func NewRecorder() *Recorder {
+	return &Recorder{begin: time.Now()}
+}
+
+// Add は件数 c に n を加算します
+func (r *Recorder) Add(c Counter, n int) {
+	r.counters[c].Add(int64(n))
+}
+
+// Count は件数 c を返します
+func (r *Recorder) Count(c Counter) int64 {
+	return r.counters[c].Load()
+}
+
+// Start はフェーズ phase の区間 name の計測を開始し、計測を終了する関数を返します
+func (r *Recorder) Start(phase, name string) func() {
+	r.mu.Lock()
+	lane := slices.Index(r.lanes, false)
+	if lane < 0 {
+		lane = len(r.lanes)
+		r.lanes = append(r.lanes, true)
+	}
+	r.lanes[lane] = true
+	r.mu.Unlock()
+
+	start := time.Since(r.begin)
+	return func() {
+		end := time.Since(r.begin)
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.lanes[lane] = false
+		r.spans = append(r.spans, span{phase: phase, name: name, start: start, end: end, lane: lane})
+	}
+}
+
+// PhaseStat はフェーズごとの集計です
+type PhaseStat struct {
+	// Phase はフェーズ名
+	Phase string
+	// Count は区間の数
+	Count int
+	// Wall は区間の和集合の長さ (並列に実行された区間は重ねて数えない)
+	Wall time.Duration
+	// Total は区間の長さの合計
+	Total time.Duration
+}
+
+// Phases はフェーズごとの集計を、最初の区間が始まった順に返します
+func (r *Recorder) Phases() []PhaseStat {
+	r.mu.Lock()
+	spans := slices.Clone(r.spans)
+	r.mu.Unlock()
+	slices.SortStableFunc(spans, func(a, b span) int {
+		return cmp.Compare(a.start, b.start)
+	})
+
+	var (
+		order  []string
+		stats  = make(map[string]*PhaseStat)
+		covers = make(map[string]time.Duration)
+	)
+	for _, s := range spans {
+		st, ok := stats[s.phase]
+		if !ok {
+			st = &PhaseStat{Phase: s.phase}
+			stats[s.phase] = st
+			order = append(order, s.phase)
+		}
+		st.Count++
+		st.Total += s.end - s.start
+		// 開始順に並んでいるため、直前までの区間の終端より後ろの部分だけを加算する
+		from := max(s.start, covers[s.phase])
+		if s.end > from {
+			st.Wall += s.end - from
+			covers[s.phase] = s.end
+		}
+	}
+	result := make([]PhaseStat, 0, len(order))
+	for _, p := range order {
+		result = append(result, *stats[p])
+	}
+	return result
+}
+
+// Print は集計を w に出力します
+func (r *Recorder) Print(w io.Writer) {
+	fmt.Fprintf(w, "stats: total %s\n", time.Since(r.begin).Round(time.Microsecond))
+	fmt.Fprintln(w, "  phases:")
+	for _, p := range r.Phases() {
+		fmt.Fprintf(w, "    %-12s wall %-12s total %-12s count %d\n", p.Phase,
+			p.Wall.Round(time.Microsecond), p.Total.Round(time.Microsecond), p.Count)
+	}
+	fmt.Fprintln(w, "  counters:")
+	for c := range numCounters {
+		fmt.Fprintf(w, "    %-18s %d\n", counterNames[c], r.Count(c))
+	}
+}
+
+// traceEvent は Chrome のトレースイベント形式の1イベントです
+type traceEvent struct {
+	Name string `json:"name"`
+	Cat  string `json:"cat"`
+	Ph   string `json:"ph"`
+	TS   int64  `json:"ts"`
+	Dur  int64  `json:"dur"`
+	PID  int    `json:"pid"`
+	TID  int    `json:"tid"`
+}
+
+// WriteTrace は記録した区間を Chrome のトレースイベント形式の JSON で w に出力します
+// chrome://tracing や Perfetto で読み込めます
+func (r *Recorder) WriteTrace(w io.Writer) error {
+	r.mu.Lock()
+	events := make([]traceEvent, 0, len(r.spans))
+	for _, s := range r.spans {
+		events = append(events, traceEvent{
+			Name: s.name,
+			Cat:  s.phase,
+			Ph:   "X",
+			TS:   s.start.Microseconds(),
+			Dur:  (s.end - s.start).Microseconds(),
+			PID:  1,
+			TID:  s.lane,
+		})
+	}
+	r.mu.Unlock()
+	slices.SortStableFunc(events, func(a, b traceEvent) int {
+		return cmp.Compare(a.TS, b.TS)
+	})
+	return json.NewEncoder(w).Encode(struct {
+		TraceEvents     []traceEvent `json:"traceEvents"`
+		DisplayTimeUnit string       `json:"displayTimeUnit"`
+	}{events, "ms"})
+}
+
+type recorderKey struct{}
+
+// WithRecorder はコンテキストに Recorder を追加します。
+func WithRecorder(ctx context.Context, r *Recorder) context.Context {
+	return context.WithValue(ctx, recorderKey{}, r)
+}
+
+// FromContext はコンテキストに紐づけられた Recorder を返します。
+// Recorder が存在しない場合は nil を返します。
+func FromContext(ctx context.Context) *Recorder {
+	r, _ := ctx.Value(recorderKey{}).(*Recorder)
+	return r
+}
+
+// Add はコンテキストに紐づけられた Recorder の件数 c に n を加算します。
+// Recorder が存在しない場合は何もしません。
+func Add(ctx context.Context, c Counter, n int) {
+	if r := FromContext(ctx); r != nil {
+		r.Add(c, n)
+	}
+}
+
+// Start はコンテキストに紐づけられた Recorder でフェーズ phase の区間 name の計測を開始し、
+// 計測を終了する関数を返します。
+// Recorder が存在しない場合は何もしない関数を返します。
+func Start(ctx context.Context, phase, name string) func() {
+	if r := FromContext(ctx); r != nil {
+		return r.Start(phase, name)
+	}
+	return func() {}
+}
@@ -0,0 +1,60 @@
+package stats_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/meian/rev-callgraph/internal/stats"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecorder(t *testing.T) {
+	r := stats.NewRecorder()
+	ctx := stats.WithRecorder(context.Background(), r)
+
+	// 並列に実行された区間は wall では重ねて数えない
+	endA := stats.Start(ctx, stats.PhaseSearch, "a")
+	endB := stats.Start(ctx, stats.PhaseSearch, "b")
+	time.Sleep(10 * time.Millisecond)
+	endA()
+	endB()
+	endC := stats.Start(ctx, stats.PhaseParse, "c")
+	endC()
+	stats.Add(ctx, stats.FilesParsed, 2)
+	stats.Add(ctx, stats.FilesParsed, 3)
+
+	phases := r.Phases()
+	require.Len(t, phases, 2)
+	assert.Equal(t, stats.PhaseSearch, phases[0].Phase)
+	assert.Equal(t, 2, phases[0].Count)
+	assert.Less(t, phases[0].Wall, phases[0].Total)
+	assert.Equal(t, stats.PhaseParse, phases[1].Phase)
+	assert.Equal(t, int64(5), r.Count(stats.FilesParsed))
+
+	var buf bytes.Buffer
+	require.NoError(t, r.WriteTrace(&buf))
+	var trace struct {
+		TraceEvents []struct {
+			Name string `json:"name"`
+			Cat  string `json:"cat"`
+			Ph   string `json:"ph"`
+			TID  int    `json:"tid"`
+		} `json:"traceEvents"`
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &trace))
+	require.Len(t, trace.TraceEvents, 3)
+	// 同時に計測中の区間は別の行に配置する
+	assert.NotEqual(t, trace.TraceEvents[0].TID, trace.TraceEvents[1].TID)
+	assert.Equal(t, "X", trace.TraceEvents[2].Ph)
+	assert.Equal(t, "c", trace.TraceEvents[2].Name)
+}
+
+func TestStart_NoRecorder(t *testing.T) {
+	// Recorder がなくても呼び出せる
+	stats.Start(context.Background(), stats.PhaseScan, "x")()
+	stats.Add(context.Background(), stats.Nodes, 1)
+}
@@ -0,0 +1,287 @@
+// Package summary はGoソースファイルから解析に必要な宣言と呼び出し箇所を抽出した要約を提供します
+package summary
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+)
+
+// Pos はファイル内の位置を表します
+type Pos struct {
+	// Offset はファイル先頭からのバイトオフセット
+	Offset int
+	// Line は行番号 (1始まり)
+	Line int
+	// Column は列番号 (1始まり, バイト単位)
+	Column int
+}
+
+// File はファイル単位の要約を表します
+type File struct {
+	// Package は package 宣言名
+	Package string
+	// Imports はインポート一覧
+	Imports []Import
+	// Funcs はトップレベルの関数/メソッド宣言一覧
+	Funcs []Func
+	// VarCalls はパッケージレベルの変数宣言の初期化式 (関数リテラルを含む) の呼び出し箇所一覧
+	// パッケージの初期化時に実行されるため、init 関数からの呼び出しとして扱えます
+	VarCalls []Call
+	// VarRefs はパッケージレベルの変数宣言の初期化式で呼び出さずに参照した識別子の一覧 (Func.Refs を参照)
+	VarRefs []Call
+	// VarPos は呼び出しまたは参照を含む最初の変数宣言の位置
+	VarPos Pos
+}
+
+// Import はインポート宣言を表します
+type Import struct {
+	// Name は明示されたインポート名 (省略時は空)
+	Name string
+	// Path はインポートパス
+	Path string
+}
+
+// Func は関数/メソッド宣言を表します
+type Func struct {
+	// Name は関数名またはメソッド名
+	Name string
+	// Recv はレシーバの型名 (関数の場合は空)
+	Recv string
+	// PointerRecv はレシーバがポインタ型かどうか
+	PointerRecv bool
+	// Pos は関数名の位置
+	Pos Pos
+	// Doc はドキュメントコメント
+	Doc string
+	// Directives はドキュメントコメント内のディレクティブ (//deadcode:ignore など) を "//" を除いて並べたもの
+	Directives []string
+	// HasBody はボディを持つかどうか
+	HasBody bool
+	// BodyStart, BodyEnd はボディのバイトオフセット範囲 [BodyStart, BodyEnd)
+	BodyStart, BodyEnd int
+	// BodyStartLine, BodyEndLine はボディの開始と終了の括弧の行番号 (1始まり)
+	BodyStartLine, BodyEndLine int
+	// Calls はボディ内 (関数リテラルを含む) の呼び出し箇所一覧
+	Calls []Call
+	// Refs はボディ内で呼び出さずに参照した識別子 (関数値・メソッド値として渡された関数など) の一覧
+	// 型を解決しないため変数やフィールドの参照も含み、同じ参照は最初の位置のみ保持します
+	Refs []Call
+}
+
+// IsMethod はメソッドかどうかを判定します
+func (f Func) IsMethod() bool {
+	return f.Recv != ""
+}
+
+// Call は呼び出し箇所を表します
+type Call struct {
+	// Name は呼び出された識別子名 (セレクタの場合は Sel 部分)
+	Name string
+	// Selector はセレクタ式 (X.Name) による呼び出しかどうか
+	Selector bool
+	// X はセレクタ式の X が識別子の場合のその名前 (それ以外は空)
+	X string
+	// Pos は呼び出し式の位置
+	Pos Pos
+}
+
+// Parse はファイルを解析して要約を作成します
+func Parse(path string, src []byte) (*File, error) {
+	fset := token.NewFileSet()
+	node, err := parser.ParseFile(fset, path, src, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		return nil, err
+	}
+	pos := func(p token.Pos) Pos {
+		position := fset.Position(p)
+		return Pos{Offset: position.Offset, Line: position.Line, Column: position.Column}
+	}
+
+	f := &File{Package: node.Name.Name}
+	for _, imp := range node.Imports {
+		i := Import{Path: strings.Trim(imp.Path.Value, `"`)}
+		if imp.Name != nil {
+			i.Name = imp.Name.Name
+		}
+		f.Imports = append(f.Imports, i)
+	}
+	for _, decl := range node.Decls {
+		if gd, ok := decl.(*ast.GenDecl); ok && gd.Tok == token.VAR {
+			c, r := calls(gd, pos), refs(gd, pos)
+			if len(f.VarCalls) == 0 && len(f.VarRefs) == 0 && len(c)+len(r) > 0 {
+				f.VarPos = pos(gd.Pos())
+			}
+			f.VarCalls = append(f.VarCalls, c...)
+			f.VarRefs = append(f.VarRefs, r...)
+			continue
+		}
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok {
+			continue
+		}
+		sf := Func{Name: fn.Name.Name, Pos: pos(fn.Name.Pos())}
+		if fn.Doc != nil {
+			sf.Doc = strings.TrimSpace(fn.Doc.Text())
+			sf.Directives = directives(fn.Doc)
+		}
+		if fn.Recv != nil && len(fn.Recv.List) > 0 {
+			sf.Recv, sf.PointerRecv = ReceiverType(fn.Recv.List[0].Type)
+			if sf.Recv == "" {
+				continue
+			}
+		}
+		if fn.Body != nil {
+			sf.HasBody = true
+			sf.BodyStart = pos(fn.Body.Pos()).Offset
+			sf.BodyEnd = pos(fn.Body.End()).Offset
+			sf.BodyStartLine = pos(fn.Body.Lbrace).Line
+			sf.BodyEndLine = pos(fn.Body.Rbrace).Line
+			sf.Calls = calls(fn.Body, pos)
+			sf.Refs = refs(fn.Body, pos)
+		}
+		f.Funcs = append(f.Funcs, sf)
+	}
+	return f, nil
+}
+
+// calls は node 内 (関数リテラルを含む) の呼び出し箇所を出現順に返します
+func calls(node ast.Node, pos func(token.Pos) Pos) []Call {
+	var result []Call
+	ast.Inspect(node, func(n ast.Node) bool {
+		ce, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		switch fun := ce.Fun.(type) {
+		case *ast.SelectorExpr:
+			c := Call{Name: fun.Sel.Name, Selector: true, Pos: pos(ce.Pos())}
+			if x, ok := fun.X.(*ast.Ident); ok {
+				c.X = x.Name
+			}
+			result = append(result, c)
+		case *ast.Ident:
+			result = append(result, Call{Name: fun.Name, Pos: pos(ce.Pos())})
+		}
+		return true
+	})
+	return result
+}
+
+// refs は node 内 (関数リテラルを含む) で呼び出し以外に現れた識別子とセレクタ式を出現順に返します
+// セレクタ式の X が識別子の場合、X は参照として扱いません
+// 同じ名前の参照 (Name, Selector, X が等しいもの) は最初の位置のみ返します
+func refs(node ast.Node, pos func(token.Pos) Pos) []Call {
+	var result []Call
+	seen := make(map[Call]bool)
+	add := func(c Call) {
+		key := Call{Name: c.Name, Selector: c.Selector, X: c.X}
+		if c.Name == "_" || seen[key] {
+			return
+		}
+		seen[key] = true
+		result = append(result, c)
+	}
+	called := make(map[ast.Expr]bool)
+	var visit func(n ast.Node) bool
+	visit = func(n ast.Node) bool {
+		switch e := n.(type) {
+		case *ast.CallExpr:
+			called[ast.Unparen(e.Fun)] = true
+		case *ast.SelectorExpr:
+			x, isIdent := e.X.(*ast.Ident)
+			if !called[e] {
+				c := Call{Name: e.Sel.Name, Selector: true, Pos: pos(e.Pos())}
+				if isIdent {
+					c.X = x.Name
+				}
+				add(c)
+			}
+			if !isIdent {
+				ast.Inspect(e.X, visit)
+			}
+			return false
+		case *ast.Ident:
+			if !called[e] {
+				add(Call{Name: e.Name, Pos: pos(e.Pos())})
+			}
+		}
+		return true
+	}
+	ast.Inspect(node, visit)
+	return result
+}
+
+// ImportMap はエイリアスまたはパッケージ名 -> インポートパスのマップを作成します
+// ブランクインポートはパスの末尾要素を名前として扱います
+func (f *File) ImportMap() map[string]string {
+	importMap := make(map[string]string)
+	for _, imp := range f.Imports {
+		alias := ""
+		if imp.Name != "" && imp.Name != "_" {
+			alias = imp.Name
+		} else {
+			parts := strings.Split(imp.Path, "/")
+			alias = parts[len(parts)-1]
+		}
+		importMap[alias] = imp.Path
+	}
+	return importMap
+}
+
+// ReceiverType はレシーバの型式から型名とポインタレシーバかどうかを返します
+// 括弧付きの型やジェネリック型 (T[K], T[K, V]) にも対応します
+func ReceiverType(expr ast.Expr) (string, bool) {
+	ptr := false
+	for {
+		switch e := expr.(type) {
+		case *ast.ParenExpr:
+			expr = e.X
+		case *ast.StarExpr:
+			ptr = true
+			expr = e.X
+		case *ast.IndexExpr:
+			expr = e.X
+		case *ast.IndexListExpr:
+			expr = e.X
+		case *ast.Ident:
+			return e.Name, ptr
+		default:
+			return "", ptr
+		}
+	}
+}
+
+// directives はコメントグループからディレクティブ (//tool:name 形式の行) を取り出します
+// ast.CommentGroup.Text はディレクティブを除外するため個別に取り出します
+func directives(cg *ast.CommentGroup) []string {
+	var result []string
+	for _, c := range cg.List {
+		text, ok := strings.CutPrefix(c.Text, "//")
+		if !ok || !isDirective(text) {
+			continue
+		}
+		result = append(result, strings.TrimSpace(text))
+	}
+	return result
+}
+
+// isDirective は "//" を除いたコメントが //tool:name 形式のディレクティブかどうかを判定します
+// go/ast の判定と同様に、コロンの前が英小文字・数字のみで、コロンの直後が英小文字・数字の場合とします
+func isDirective(text string) bool {
+	colon := strings.Index(text, ":")
+	if colon <= 0 || colon+1 >= len(text) {
+		return false
+	}
+	for i := 0; i <= colon+1; i++ {
+		if i == colon {
+			continue
+		}
+		b := text[i]
+		if !('a' <= b && b <= 'z' || '0' <= b && b <= '9') {
+			return false
+		}
+	}
+	return true
+}